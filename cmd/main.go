@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"github.com/eriksywu/ascii/cmd/server"
 	"github.com/eriksywu/ascii/pkg/filestore"
 	"github.com/eriksywu/ascii/pkg/image"
@@ -13,12 +14,18 @@ const defaultStorePath = "/asciistore"
 //we can mount a hostvolume or pvc to persist
 var StorePath = defaultStorePath
 
+var defaultMode = flag.String("mode", string(image.ModeASCII), "render mode used for requests that don't specify one")
+
 func main() {
+	flag.Parse()
 	imageStore, err := filestore.NewStore(StorePath)
 	if err != nil {
 		log.Fatal(err)
 	}
 	asciiService := image.NewService(imageStore)
+	if err := asciiService.SetDefaultMode(image.RenderMode(*defaultMode)); err != nil {
+		log.Fatal(err)
+	}
 	app := server.BuildServer(asciiService, 8000)
 	app.Run()
 }
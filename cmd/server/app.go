@@ -1,17 +1,22 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/eriksywu/ascii/pkg/export"
 	"github.com/eriksywu/ascii/pkg/image"
 	"github.com/eriksywu/ascii/pkg/logging"
 	"github.com/eriksywu/ascii/pkg/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // singleton instance of the ascii image server
@@ -53,16 +58,26 @@ func buildRouter (s *appServer) {
 	router := mux.NewRouter()
 
 	router.HandleFunc(baseURL, s.newImageBaseHandler().
+		WithPlainText().
 		WithLoggingContext("newImageHandler").
 		WithDynamicTimeout(s.dynamicTimeoutFunc)).
 		Methods("POST")
 
 	router.HandleFunc(baseURL, s.getImageListBaseHandler().
+		WithPlainText().
 		WithLoggingContext("getImageListHandler").
 		WithTimeout(30)).
 		Methods("GET")
 
+	// registered before /images/{imageId} since that route would match the extension as part of the id
+	router.HandleFunc(baseURL+"/{imageId}.{"+formatVar+"}", s.getImageBaseHandler().
+		WithPlainText().
+		WithLoggingContext("getImageHandler").
+		WithTimeout(60)).
+		Methods("GET")
+
 	router.HandleFunc(baseURL+"/{imageId}", s.getImageBaseHandler().
+		WithPlainText().
 		WithLoggingContext("getImageHandler").
 		WithTimeout(60)).
 		Methods("GET")
@@ -86,19 +101,33 @@ func (s *appServer) dynamicTimeoutFunc(r *http.Request) int {
 func (s *appServer) newImageBaseHandler() httpMiddleWare {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		var uid *uuid.UUID
-		var err error
+		var format string
+		opts, err := parseConversionOptions(r)
+		if err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		}
+		// uploads only answer with the image itself when asked to, since scripts rely on the json image id
+		plain, err := parsePlainText(r, false)
+		if err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		}
 		if r.Header.Get("async") == "true" {
-			uid, err = s.service.NewASCIIImageAsync(r.Context(), r.Body)
+			uid, format, err = s.service.NewASCIIImageAsync(r.Context(), r.Body, opts)
 		} else {
-			uid, err = s.service.NewASCIIImageSync(r.Context(), r.Body)
+			uid, format, err = s.service.NewASCIIImageSync(r.Context(), r.Body, opts)
 		}
 		if err != nil {
 			s.writeErrorResponse(r.Context(), err, rw)
 		} else if uid == nil {
 			s.writeErrorResponse(r.Context(), fmt.Errorf("internal error: could not generate uuid"), rw)
+		} else if plain != "" && r.Header.Get("async") != "true" {
+			s.writeNewImage(r.Context(), rw, *uid, plain)
 		} else {
 			response := models.NewImageResponse{
 				ImageID:  uid.String(),
+				Format:   format,
 			}
 			responseBody, _ := json.Marshal(response)
 			rw.Write([]byte(responseBody))
@@ -106,28 +135,78 @@ func (s *appServer) newImageBaseHandler() httpMiddleWare {
 	}
 }
 
+// writeNewImage answers an upload with the finished image as plain text, the image's location is returned in the Location header
+func (s *appServer) writeNewImage(ctx context.Context, rw http.ResponseWriter, uid uuid.UUID, format string) {
+	_, asciiImage, err := s.service.GetASCIIImage(ctx, uid)
+	if err != nil {
+		s.writeErrorResponse(ctx, err, rw)
+		return
+	}
+	rw.Header().Set("Location", baseURL+"/"+uid.String())
+	s.writeRawImage(rw, asciiImage, format)
+}
+
 func (s *appServer) getImageBaseHandler() httpMiddleWare {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		// the response depends on the Accept header and the client when no format is given
+		rw.Header().Set("Vary", acceptHeader+", User-Agent")
 		imageId := mux.Vars(r)["imageId"]
 		imageUID, err := uuid.Parse(imageId)
 		if err != nil {
 			s.writeErrorResponse(r.Context(), err, rw)
 			return
 		}
-		finished, imageBytes, err := s.service.GetASCIIImage(r.Context(), imageUID)
+		format, err := parseFormat(r)
+		if err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		}
+		exportOpts, err := parseExportOptions(r)
+		if err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		}
+		colorMode, colorDither, err := parsePalette(r)
+		if err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		}
+		// cells are stored apart from the image and only loaded for the formats that use them
+		getImage := s.service.GetASCIIImage
+		if format == formatCells || format == formatNDJSON {
+			getImage = s.service.GetASCIIImageCells
+		}
+		finished, asciiImage, err := getImage(r.Context(), imageUID)
 		// if it's an internalprocessingerror, return the error in the response body
 		if err != nil && !errors.Is(err, image.InternalProcessingError{}) {
 			s.writeErrorResponse(r.Context(), err, rw)
 			return
 		}
+		if finished && asciiImage != nil {
+			if asciiImage, err = asciiImage.Downsample(r.Context(), colorMode, colorDither); err != nil {
+				s.writeErrorResponse(r.Context(), err, rw)
+				return
+			}
+		}
+		// raw formats are only available once the image has finished, otherwise fall through to the json status response
+		if finished && asciiImage != nil && format != formatJSON {
+			s.writeFormattedImage(r.Context(), rw, imageUID, asciiImage, format, exportOpts)
+			return
+		}
 		response := models.GetImageResponse{
-			ASCIIValue: string(imageBytes),
-			Finished:   finished,
+			Finished: finished,
+		}
+		if asciiImage != nil {
+			response.ASCIIValue = asciiImage.Value
+			response.ANSIValue = asciiImage.ANSIValue
+			response.SourceFormat = asciiImage.SourceFormat
+			response.RenderOptions = toRenderOptionsModel(asciiImage.Options)
 		}
 		if err != nil {
 			response.ErrorMessage = err.Error()
 		}
 		responseBody, _ := json.Marshal(response)
+		rw.Header().Set("Content-Type", "application/json")
 		rw.Write([]byte(responseBody))
 	}
 }
@@ -143,6 +222,14 @@ func (s *appServer) getImageListBaseHandler() httpMiddleWare {
 		for _, id := range imageIDList {
 			imageList = append(imageList, id.String())
 		}
+		if plain, err := parsePlainText(r, isTerminalClient(r)); err != nil {
+			s.writeErrorResponse(r.Context(), err, rw)
+			return
+		} else if plain != "" {
+			rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			rw.Write([]byte(strings.Join(imageList, "\n") + "\n"))
+			return
+		}
 		response := models.GetImageListResponse{
 			ImageIDList: imageList,
 		}
@@ -151,14 +238,204 @@ func (s *appServer) getImageListBaseHandler() httpMiddleWare {
 	}
 }
 
+func (s *appServer) writeFormattedImage(ctx context.Context, rw http.ResponseWriter, id uuid.UUID, asciiImage *image.ASCIIImage, format string, opts export.Options) {
+	switch format {
+	case formatPNG, formatSVG, formatHTML, formatANS, formatMarkdown, formatSlack, formatDiscord, formatIRC:
+		if exporters[format].download {
+			rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", id, format))
+		}
+		s.writeExport(ctx, rw, asciiImage, exporters[format], opts)
+	case formatFrames:
+		s.writeFramesResponse(rw, asciiImage)
+	case formatCells:
+		s.writeCellsResponse(rw, asciiImage)
+	case formatNDJSON:
+		s.writeCellsNDJSON(rw, asciiImage)
+	case formatMovie:
+		s.writeMovie(ctx, rw, asciiImage)
+	default:
+		s.writeRawImage(rw, asciiImage, format)
+	}
+}
+
+func (s *appServer) writeRawImage(rw http.ResponseWriter, asciiImage *image.ASCIIImage, format string) {
+	value := asciiImage.Value
+	if format == formatANSI && asciiImage.ANSIValue != "" {
+		value = asciiImage.ANSIValue
+	}
+	// terminals print the next prompt straight after the image otherwise
+	if !strings.HasSuffix(value, "\n") {
+		value += "\n"
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write([]byte(value))
+}
+
+// exporter writes an image in one of the export formats
+type exporter struct {
+	contentType string
+	write       func(io.Writer, image.Grid, export.Options) error
+	// download formats are served as attachments, since browsers can't show them
+	download bool
+	// monochrome formats are drawn from the monochrome rendition, since they have no way to show colors
+	monochrome bool
+	// bitmapFont formats draw characters with export's bitmap font, which has no emoji
+	bitmapFont bool
+}
+
+var exporters = map[string]exporter{
+	formatPNG:  {contentType: "image/png", write: export.WritePNG, bitmapFont: true},
+	formatSVG:  {contentType: "image/svg+xml", write: export.WriteSVG},
+	formatHTML: {contentType: "text/html; charset=utf-8", write: export.WriteHTML},
+	formatANS:  {contentType: "application/octet-stream", write: export.WriteANS, download: true},
+	// formats for pasting into chat and docs, scaled down to the platform's line limit
+	formatMarkdown: {contentType: "text/markdown; charset=utf-8", write: export.WriteMarkdown, monochrome: true},
+	formatSlack:    {contentType: "text/plain; charset=utf-8", write: export.WriteSlack, monochrome: true},
+	formatDiscord:  {contentType: "text/plain; charset=utf-8", write: export.WriteDiscord, monochrome: true},
+	formatIRC:      {contentType: "text/plain; charset=utf-8", write: export.WriteIRC},
+}
+
+// writeExport draws the image's colored rendition, or its monochrome one if it has none or the format is monochrome
+// animated images are drawn from their first frame
+func (s *appServer) writeExport(ctx context.Context, rw http.ResponseWriter, asciiImage *image.ASCIIImage, e exporter, opts export.Options) {
+	if e.bitmapFont && asciiImage.Options.Mode == image.ModeEmoji {
+		s.writeErrorResponse(ctx, image.NewInvalidInputError(fmt.Errorf("%s images can't be drawn with the bitmap font, every emoji would be a '?'", image.ModeEmoji)), rw)
+		return
+	}
+	opts.Title, opts.Author = asciiImage.Options.Title, asciiImage.Options.Author
+	grid := asciiImage.Grid()
+	if e.monochrome {
+		grid = image.ParseANSI(asciiImage.Value)
+	}
+	// exports are buffered so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	if err := e.write(&buf, grid, opts); err != nil {
+		s.writeErrorResponse(ctx, err, rw)
+		return
+	}
+	rw.Header().Set("Content-Type", e.contentType)
+	rw.Write(buf.Bytes())
+}
+
+func (s *appServer) writeFramesResponse(rw http.ResponseWriter, asciiImage *image.ASCIIImage) {
+	frames := imageFrames(asciiImage)
+	response := models.GetImageFramesResponse{
+		Frames:    make([]models.Frame, 0, len(frames)),
+		LoopCount: asciiImage.LoopCount,
+	}
+	for _, frame := range frames {
+		response.Frames = append(response.Frames, models.Frame{
+			ASCIIValue: frame.Value,
+			ANSIValue:  frame.ANSIValue,
+			DelayMs:    frame.Delay.Milliseconds(),
+		})
+	}
+	responseBody, _ := json.Marshal(response)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(responseBody)
+}
+
+// terminal escapes used to play back animations in place
+const (
+	clearScreen = "\x1b[2J"
+	cursorHome  = "\x1b[H"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+)
+
+// writeMovie streams every frame of the image, moving the cursor back to the top left before each frame so it draws over the last one
+// animations that loop forever keep playing until the client disconnects or the request times out
+func (s *appServer) writeMovie(ctx context.Context, rw http.ResponseWriter, asciiImage *image.ASCIIImage) {
+	frames := imageFrames(asciiImage)
+	loops := asciiImage.LoopCount
+	if len(asciiImage.Frames) == 0 {
+		// a still image is a single frame that plays once
+		loops = 1
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := rw.(http.Flusher)
+	rw.Write([]byte(clearScreen + hideCursor))
+	defer rw.Write([]byte(showCursor))
+	for loop := 0; loops == 0 || loop < loops; loop++ {
+		for _, frame := range frames {
+			value := frame.ANSIValue
+			if value == "" {
+				value = frame.Value
+			}
+			rw.Write([]byte(cursorHome + value))
+			if flusher != nil {
+				flusher.Flush()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(frame.Delay):
+			}
+		}
+	}
+}
+
+// imageFrames returns the frames of an animated image, or the image itself as a single frame if it isn't animated
+func imageFrames(asciiImage *image.ASCIIImage) []image.Frame {
+	if len(asciiImage.Frames) > 0 {
+		return asciiImage.Frames
+	}
+	return []image.Frame{{Value: asciiImage.Value, ANSIValue: asciiImage.ANSIValue}}
+}
+
+func toRenderOptionsModel(opts image.ConversionOptions) models.RenderOptions {
+	var adjustments []string
+	for _, adjustment := range opts.Adjustments {
+		adjustments = append(adjustments, adjustment.String())
+	}
+	background := ""
+	if opts.Alpha == image.AlphaComposite {
+		background = image.FormatColor(opts.Background)
+	}
+	return models.RenderOptions{
+		Mode:              string(opts.Mode),
+		Width:             opts.Width,
+		Height:            opts.Height,
+		Scale:             opts.Scale,
+		Color:             string(opts.Color),
+		ColorDither:       string(opts.ColorDither),
+		Ramp:              opts.Ramp,
+		Invert:            opts.Invert,
+		Animate:           opts.Animate,
+		Threshold:         opts.Threshold,
+		Dither:            string(opts.Dither),
+		Adjustments:       adjustments,
+		Crop:              opts.Crop.String(),
+		Rotate:            opts.Rotate,
+		Flip:              string(opts.Flip),
+		IgnoreOrientation: opts.IgnoreOrientation,
+		CellAspect:        opts.CellAspect,
+		Alpha:             string(opts.Alpha),
+		Background:        background,
+		AlphaThreshold:    opts.AlphaThreshold,
+		EdgeDetector:      string(opts.EdgeDetector),
+		EdgeOverlay:       opts.EdgeOverlay,
+		EmojiPalette:      string(opts.EmojiPalette),
+		Resample:          string(opts.Resample),
+		Title:             opts.Title,
+		Author:            opts.Author,
+	}
+}
+
 func (s *appServer) writeErrorResponse(ctx context.Context, err error, rw http.ResponseWriter) {
 	switch err.(type) {
 	case image.InternalProcessingError:
 		rw.WriteHeader(http.StatusInternalServerError)
 	case image.ResourceNotFoundError:
 		rw.WriteHeader(http.StatusNotFound)
+	case notAcceptableError:
+		rw.WriteHeader(http.StatusNotAcceptable)
 	case image.InvalidInputError:
-		rw.WriteHeader(http.StatusBadRequest)
+		if errors.Is(err, image.UnsupportedFormatError) {
+			rw.WriteHeader(http.StatusUnsupportedMediaType)
+		} else {
+			rw.WriteHeader(http.StatusBadRequest)
+		}
 	default:
 		rw.WriteHeader(http.StatusBadRequest)
 	}
@@ -2,40 +2,53 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/eriksywu/ascii/pkg/logging"
+	"github.com/eriksywu/ascii/pkg/models"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	stdimage "image"
+	"image/color"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 var _ ASCIIImageService = (*ASCIIImageServiceMock)(nil)
 
 type ASCIIImageServiceMock struct {
-	GetASCIIImageFn    func() (bool, []byte, error)
-	GetNewASCIIImageFn func() (*uuid.UUID, error)
+	GetASCIIImageFn    func() (bool, *image.ASCIIImage, error)
+	GetNewASCIIImageFn func() (*uuid.UUID, string, error)
 	GetImageListFn     func() ([]uuid.UUID, error)
 }
 
-func (A ASCIIImageServiceMock) GetASCIIImage(_ context.Context, _ uuid.UUID) (bool, []byte, error) {
+func (A ASCIIImageServiceMock) GetASCIIImage(_ context.Context, _ uuid.UUID) (bool, *image.ASCIIImage, error) {
 	if A.GetASCIIImageFn == nil {
 		return false, nil, nil
 	}
 	return A.GetASCIIImageFn()
 }
 
-func (A ASCIIImageServiceMock) NewASCIIImageAsync(_ context.Context, _ io.ReadCloser) (*uuid.UUID, error) {
+func (A ASCIIImageServiceMock) GetASCIIImageCells(ctx context.Context, id uuid.UUID) (bool, *image.ASCIIImage, error) {
+	return A.GetASCIIImage(ctx, id)
+}
+
+func (A ASCIIImageServiceMock) NewASCIIImageAsync(_ context.Context, _ io.ReadCloser, _ image.ConversionOptions) (*uuid.UUID, string, error) {
 	if A.GetNewASCIIImageFn == nil {
-		return nil, nil
+		return nil, "", nil
 	}
 	return A.GetNewASCIIImageFn()
 }
 
-func (A ASCIIImageServiceMock) NewASCIIImageSync(_ context.Context, _ io.ReadCloser) (*uuid.UUID, error) {
+func (A ASCIIImageServiceMock) NewASCIIImageSync(_ context.Context, _ io.ReadCloser, _ image.ConversionOptions) (*uuid.UUID, string, error) {
 	if A.GetNewASCIIImageFn == nil {
-		return nil, nil
+		return nil, "", nil
 	}
 	return A.GetNewASCIIImageFn()
 }
@@ -71,7 +84,7 @@ func TestGetASCIIImageHandler_ServiceFails(t *testing.T) {
 	}
 
 	mockService := &ASCIIImageServiceMock{}
-	mockService.GetASCIIImageFn = func() (bool, []byte, error) {
+	mockService.GetASCIIImageFn = func() (bool, *image.ASCIIImage, error) {
 		return false, nil, errors.New("some error")
 	}
 	testSubject := BuildServer(mockService, 8080)
@@ -84,4 +97,315 @@ func TestGetASCIIImageHandler_ServiceFails(t *testing.T) {
 	assert.NotEqual(t, status, http.StatusOK)
 }
 
+// newTestServer builds an appServer around the given service without touching the BuildServer singleton
+func newTestServer(service ASCIIImageService) *appServer {
+	return &appServer{service: service, logger: logging.Logger}
+}
+
+func TestNewImageHandler(t *testing.T) {
+	id := uuid.New()
+	testCases := []struct {
+		target   string
+		newImage func() (*uuid.UUID, string, error)
+		code     int
+		response *models.NewImageResponse
+	}{
+		// the service isn't called with invalid options
+		{"/images?width=-1", nil, http.StatusBadRequest, nil},
+		{"/images", func() (*uuid.UUID, string, error) { return nil, "", image.UnsupportedFormatError }, http.StatusUnsupportedMediaType, nil},
+		{"/images", func() (*uuid.UUID, string, error) { return &id, "jpeg", nil }, http.StatusOK, &models.NewImageResponse{ImageID: id.String(), Format: "jpeg"}},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest("POST", tc.target, strings.NewReader("some image"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mockService := &ASCIIImageServiceMock{GetNewASCIIImageFn: tc.newImage}
+		if tc.newImage == nil {
+			mockService.GetNewASCIIImageFn = func() (*uuid.UUID, string, error) {
+				t.Errorf("service shouldn't be called with invalid options")
+				return nil, "", nil
+			}
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(newTestServer(mockService).newImageBaseHandler())
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, tc.code, rr.Code, tc.target)
+		if tc.response != nil {
+			var response models.NewImageResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Equal(t, *tc.response, response)
+		}
+	}
+}
+
+// getImage serves a GET of target, relative to the url of an image, from a service that returns asciiImage
+func getImage(t *testing.T, asciiImage *image.ASCIIImage, target string) *httptest.ResponseRecorder {
+	mockService := &ASCIIImageServiceMock{}
+	mockService.GetASCIIImageFn = func() (bool, *image.ASCIIImage, error) {
+		return asciiImage != nil, asciiImage, nil
+	}
+	s := newTestServer(mockService)
+	buildRouter(s)
+	req, err := http.NewRequest("GET", "/images/"+uuid.New().String()+target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestGetASCIIImageHandler_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		image  *image.ASCIIImage
+		target string
+	}{
+		{nil, "?format=bmp"},
+		{nil, "?format=png&fontsize=1000"},
+		{nil, "?color=rainbow"},
+		// the bitmap font has no emoji
+		{&image.ASCIIImage{Value: "🟥\n", Options: image.ConversionOptions{Mode: image.ModeEmoji}}, "?format=png"},
+	}
+	for _, tc := range testCases {
+		rr := getImage(t, tc.image, tc.target)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, tc.target)
+	}
+}
+
+func TestGetASCIIImageHandler_JSON(t *testing.T) {
+	trueColor := image.Grid{{{Char: '@', Color: color.NRGBA{R: 250, G: 10, B: 10, A: 255}}}}
+	red, blue := color.NRGBA{R: 255, A: 255}, color.NRGBA{B: 255, A: 255}
+	luminance, zero := 0.25, 0.0
+	testCases := []struct {
+		image    *image.ASCIIImage
+		target   string
+		response interface{}
+		expected interface{}
+	}{
+		{
+			&image.ASCIIImage{Value: "@@", Options: image.ConversionOptions{Width: 2, Height: 1}}, "",
+			&models.GetImageResponse{}, &models.GetImageResponse{ASCIIValue: "@@", Finished: true, RenderOptions: models.RenderOptions{Width: 2, Height: 1}},
+		},
+		// colored renditions are downsampled on the fly
+		{
+			&image.ASCIIImage{Value: "@\n", ANSIValue: trueColor.ANSI(image.ColorTrue), Options: image.ConversionOptions{Color: image.ColorTrue}}, "?color=16",
+			&models.GetImageResponse{}, &models.GetImageResponse{ASCIIValue: "@\n", ANSIValue: "\x1b[91m@\x1b[0m\n", Finished: true, RenderOptions: models.RenderOptions{Color: "16"}},
+		},
+		{
+			&image.ASCIIImage{Value: "a\n", LoopCount: 2, Frames: []image.Frame{{Value: "a\n", Delay: 100 * time.Millisecond}, {Value: "b\n", Delay: 50 * time.Millisecond}}}, "?format=frames",
+			&models.GetImageFramesResponse{}, &models.GetImageFramesResponse{LoopCount: 2, Frames: []models.Frame{{ASCIIValue: "a\n", DelayMs: 100}, {ASCIIValue: "b\n", DelayMs: 50}}},
+		},
+		{
+			&image.ASCIIImage{Value: "█ \n", Cells: image.Grid{{{Char: '█', ColorChar: '▀', Color: red, Background: &blue, Luminance: 0.25}, {Char: ' ', Transparent: true}}}}, "?format=cells",
+			&models.GetImageCellsResponse{}, &models.GetImageCellsResponse{Width: 2, Height: 1, Rows: [][]models.Cell{{
+				{Glyph: "▀", Foreground: &models.RGB{R: 255}, Background: &models.RGB{B: 255}, Luminance: &luminance},
+				{Glyph: " ", Luminance: &zero, Transparent: true},
+			}}},
+		},
+		// cells are snapped to the palette too, bright red is the 16 color the ansi rendition is downsampled to
+		{
+			&image.ASCIIImage{Value: "@\n", ANSIValue: trueColor.ANSI(image.ColorTrue), Options: image.ConversionOptions{Color: image.ColorTrue}, Cells: trueColor}, "?format=cells&color=16",
+			&models.GetImageCellsResponse{}, &models.GetImageCellsResponse{Width: 1, Height: 1, Rows: [][]models.Cell{{{Glyph: "@", Foreground: &models.RGB{R: 255}, Luminance: &zero}}}},
+		},
+	}
+	for _, tc := range testCases {
+		rr := getImage(t, tc.image, tc.target)
+
+		assert.Equal(t, http.StatusOK, rr.Code, tc.target)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), tc.target)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), tc.response), tc.target)
+		assert.Equal(t, tc.expected, tc.response, tc.target)
+	}
+}
+
+func TestGetASCIIImageHandler_Text(t *testing.T) {
+	trueColor := &image.ASCIIImage{Value: "@\n", ANSIValue: "\x1b[38;2;250;10;10m@\x1b[0m\n", Options: image.ConversionOptions{Color: image.ColorTrue}}
+	animated := &image.ASCIIImage{Value: "a\n", LoopCount: 2, Frames: []image.Frame{{Value: "a\n", Delay: time.Millisecond}, {Value: "b\n", ANSIValue: "B\n", Delay: time.Millisecond}}}
+	movieFrames := cursorHome + "a\n" + cursorHome + "B\n"
+	chat := &image.ASCIIImage{Value: "*@\n", ANSIValue: "\x1b[38;2;255;0;0m*@\x1b[0m\n"}
+	// frames without cells are parsed back from their text
+	framesWithoutCells := &image.ASCIIImage{Value: "a\n", Frames: []image.Frame{{Value: "a\nb\n"}, {Value: "c\n", ANSIValue: "\x1b[38;2;0;255;0mc\x1b[0m\n"}}}
+	testCases := []struct {
+		image       *image.ASCIIImage
+		target      string
+		contentType string
+		body        string
+	}{
+		{&image.ASCIIImage{Value: "@\n", ANSIValue: "\x1b[38;5;196m@\x1b[0m\n"}, "?format=ansi", "text/plain; charset=utf-8", "\x1b[38;5;196m@\x1b[0m\n"},
+		{trueColor, "?format=ansi&color=16", "text/plain; charset=utf-8", "\x1b[91m@\x1b[0m\n"},
+		{trueColor, "?format=ansi&color=256", "text/plain; charset=utf-8", "\x1b[38;5;196m@\x1b[0m\n"},
+		{trueColor, "?format=ansi&color=truecolor", "text/plain; charset=utf-8", "\x1b[38;2;250;10;10m@\x1b[0m\n"},
+		{trueColor, "?format=ansi", "text/plain; charset=utf-8", "\x1b[38;2;250;10;10m@\x1b[0m\n"},
+		{animated, "?format=movie", "", clearScreen + hideCursor + movieFrames + movieFrames + showCursor},
+		{chat, ".markdown", "text/markdown; charset=utf-8", "```text\n*@\n```\n"},
+		{chat, ".slack", "text/plain; charset=utf-8", "```\n+@\n```\n"},
+		{chat, ".discord", "text/plain; charset=utf-8", "```\n+@\n```\n"},
+		{chat, ".irc", "text/plain; charset=utf-8", "\x0304*@\x0f\n"},
+		{framesWithoutCells, "?format=ndjson", "application/x-ndjson", `{"Frame":0,"Row":0,"Cells":[{"Glyph":"a","Foreground":null,"Background":null,"Luminance":null,"Transparent":false}]}
+{"Frame":0,"Row":1,"Cells":[{"Glyph":"b","Foreground":null,"Background":null,"Luminance":null,"Transparent":false}]}
+{"Frame":1,"Row":0,"Cells":[{"Glyph":"c","Foreground":{"R":0,"G":255,"B":0},"Background":null,"Luminance":null,"Transparent":false}]}
+`},
+	}
+	for _, tc := range testCases {
+		rr := getImage(t, tc.image, tc.target)
+
+		assert.Equal(t, http.StatusOK, rr.Code, tc.target)
+		if tc.contentType != "" {
+			assert.Equal(t, tc.contentType, rr.Header().Get("Content-Type"), tc.target)
+		}
+		assert.Equal(t, tc.body, rr.Body.String(), tc.target)
+	}
+}
+
+func TestGetASCIIImageHandler_PNGExtension(t *testing.T) {
+	asciiImage := &image.ASCIIImage{Value: "@@\n", ANSIValue: "\x1b[38;2;255;0;0m@@\x1b[0m\n"}
+	for _, target := range []string{".png?fontsize=26", "?format=png&fontsize=26"} {
+		rr := getImage(t, asciiImage, target)
+
+		assert.Equal(t, http.StatusOK, rr.Code, target)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"), target)
+		m, err := png.Decode(rr.Body)
+		assert.NoError(t, err, target)
+		assert.Equal(t, stdimage.Rect(0, 0, 28, 26), m.Bounds(), target)
+	}
+}
+
+func TestGetASCIIImageHandler_Documents(t *testing.T) {
+	for format, contentType := range map[string]string{"svg": "image/svg+xml", "html": "text/html; charset=utf-8"} {
+		rr := getImage(t, &image.ASCIIImage{Value: "<@>\n"}, "?format="+format)
+
+		assert.Equal(t, http.StatusOK, rr.Code, format)
+		assert.Equal(t, contentType, rr.Header().Get("Content-Type"), format)
+		assert.Contains(t, rr.Body.String(), "&lt;@&gt;", format)
+	}
+}
+
+func TestGetASCIIImageHandler_Accept(t *testing.T) {
+	mockService := &ASCIIImageServiceMock{}
+	mockService.GetASCIIImageFn = func() (bool, *image.ASCIIImage, error) {
+		return true, &image.ASCIIImage{Value: "@\n"}, nil
+	}
+	s := newTestServer(mockService)
+	buildRouter(s)
+
+	testCases := []struct {
+		target      string
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", "", http.StatusOK, "application/json"},
+		{"", "text/plain", http.StatusOK, "text/plain; charset=utf-8"},
+		{"", "image/png", http.StatusOK, "image/png"},
+		{"", "text/html, */*;q=0.1", http.StatusOK, "text/html; charset=utf-8"},
+		{"", "application/xml", http.StatusNotAcceptable, ""},
+		// the format param and extension take precedence over the accept header
+		{"?format=text", "application/xml", http.StatusOK, "text/plain; charset=utf-8"},
+		{".svg", "text/plain", http.StatusOK, "image/svg+xml"},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest("GET", "/images/"+uuid.New().String()+tc.target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rr := httptest.NewRecorder()
+
+		s.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, tc.code, rr.Code, "%+v", tc)
+		assert.Equal(t, "Accept, User-Agent", rr.Header().Get("Vary"), "%+v", tc)
+		if tc.contentType != "" {
+			assert.Equal(t, tc.contentType, rr.Header().Get("Content-Type"), "%+v", tc)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	imageID := uuid.New()
+	mockService := &ASCIIImageServiceMock{}
+	mockService.GetASCIIImageFn = func() (bool, *image.ASCIIImage, error) {
+		return true, &image.ASCIIImage{Value: "@", ANSIValue: "\x1b[38;5;196m@\x1b[0m\n"}, nil
+	}
+	mockService.GetNewASCIIImageFn = func() (*uuid.UUID, string, error) {
+		return &imageID, "png", nil
+	}
+	mockService.GetImageListFn = func() ([]uuid.UUID, error) {
+		return []uuid.UUID{imageID, imageID}, nil
+	}
+	s := newTestServer(mockService)
+	buildRouter(s)
+
+	testCases := []struct {
+		method    string
+		target    string
+		userAgent string
+		body      string
+	}{
+		// the raw image gets a trailing newline
+		{"GET", "/images/" + imageID.String(), "curl/7.68.0", "@\n"},
+		{"GET", "/images/" + imageID.String() + "?plain=ansi", "curl/7.68.0", "\x1b[38;5;196m@\x1b[0m\n"},
+		{"GET", "/images/" + imageID.String() + "?plain=true", "Mozilla/5.0", "@\n"},
+		{"GET", "/images", "Wget/1.20.3", imageID.String() + "\n" + imageID.String() + "\n"},
+		{"POST", "/images?plain=true", "curl/7.68.0", "@\n"},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, tc.target, strings.NewReader("some image"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", tc.userAgent)
+		rr := httptest.NewRecorder()
+
+		s.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "%+v", tc)
+		assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"), "%+v", tc)
+		assert.Equal(t, tc.body, rr.Body.String(), "%+v", tc)
+		if tc.method == "POST" {
+			assert.Equal(t, "/images/"+imageID.String(), rr.Header().Get("Location"))
+		}
+	}
+
+	// json is kept for uploads, explicit opt outs and clients asking for it
+	for _, tc := range []struct{ method, target, accept string }{
+		{"POST", "/images", ""},
+		{"GET", "/images/" + imageID.String() + "?plain=false", ""},
+		{"GET", "/images/" + imageID.String(), "application/json"},
+	} {
+		req, err := http.NewRequest(tc.method, tc.target, strings.NewReader("some image"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "curl/7.68.0")
+		req.Header.Set("Accept", tc.accept)
+		rr := httptest.NewRecorder()
+
+		s.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "%+v", tc)
+		assert.True(t, json.Valid(rr.Body.Bytes()), "%+v", tc)
+	}
+}
+
+func TestGetASCIIImageHandler_ANS(t *testing.T) {
+	rr := getImage(t, &image.ASCIIImage{Value: "@\n", Options: image.ConversionOptions{Title: "a title", Author: "someone"}}, ".ans")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="[0-9a-f-]{36}\.ans"$`, rr.Header().Get("Content-Disposition"))
+	grid, sauce := image.ParseANS(rr.Body.Bytes())
+	assert.Equal(t, "@\n", grid.String())
+	assert.Equal(t, "a title", sauce.Title)
+	assert.Equal(t, "someone", sauce.Author)
+}
+
 // Not much need to test the other handlers since they're all business logic
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/eriksywu/ascii/pkg/models"
+	"net/http"
+	"time"
+)
+
+// cellsFrame is the grid of a frame and whether it holds the cells the image was rendered with
+// images that didn't keep their cells (imported images and images stored before cells were kept) are parsed back from their text, without luminance
+type cellsFrame struct {
+	grid     image.Grid
+	rendered bool
+	delay    time.Duration
+}
+
+// imageCells returns the cells of every frame of the image, or of the image itself if it isn't animated
+func imageCells(asciiImage *image.ASCIIImage) []cellsFrame {
+	if len(asciiImage.Frames) == 0 {
+		if asciiImage.Cells != nil {
+			return []cellsFrame{{grid: asciiImage.Cells, rendered: true}}
+		}
+		return []cellsFrame{{grid: asciiImage.Grid()}}
+	}
+	frames := make([]cellsFrame, 0, len(asciiImage.Frames))
+	for _, frame := range asciiImage.Frames {
+		f := cellsFrame{grid: frame.Cells, rendered: true, delay: frame.Delay}
+		if frame.Cells == nil {
+			f.grid, f.rendered = (&image.ASCIIImage{Value: frame.Value, ANSIValue: frame.ANSIValue}).Grid(), false
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func (s *appServer) writeCellsResponse(rw http.ResponseWriter, asciiImage *image.ASCIIImage) {
+	frames := imageCells(asciiImage)
+	response := models.GetImageCellsResponse{
+		Rows:      toCellRowsModel(frames[0]),
+		LoopCount: asciiImage.LoopCount,
+	}
+	response.Height = len(response.Rows)
+	for _, row := range response.Rows {
+		if len(row) > response.Width {
+			response.Width = len(row)
+		}
+	}
+	if len(asciiImage.Frames) > 0 {
+		for _, frame := range frames {
+			response.Frames = append(response.Frames, models.CellsFrame{Rows: toCellRowsModel(frame), DelayMs: frame.delay.Milliseconds()})
+		}
+	}
+	responseBody, _ := json.Marshal(response)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(responseBody)
+}
+
+// writeCellsNDJSON streams the cells one row per line, frame by frame, so clients can start drawing before the whole image is sent
+func (s *appServer) writeCellsNDJSON(rw http.ResponseWriter, asciiImage *image.ASCIIImage) {
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(rw)
+	for n, frame := range imageCells(asciiImage) {
+		for y, row := range toCellRowsModel(frame) {
+			if err := encoder.Encode(models.CellsRow{Frame: n, Row: y, Cells: row}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func toCellRowsModel(frame cellsFrame) [][]models.Cell {
+	rows := make([][]models.Cell, len(frame.grid))
+	for y, row := range frame.grid {
+		rows[y] = make([]models.Cell, len(row))
+		for x, cell := range row {
+			rows[y][x] = toCellModel(cell, frame.rendered)
+		}
+	}
+	return rows
+}
+
+func toCellModel(cell image.Cell, rendered bool) models.Cell {
+	model := models.Cell{Glyph: string(cell.Char), Transparent: cell.Transparent}
+	// colored renditions draw ColorChar in place of Char
+	if cell.ColorChar != 0 {
+		model.Glyph = string(cell.ColorChar)
+	}
+	if !cell.Transparent && cell.Color.A != 0 {
+		model.Foreground = &models.RGB{R: cell.Color.R, G: cell.Color.G, B: cell.Color.B}
+	}
+	if cell.Background != nil {
+		model.Background = &models.RGB{R: cell.Background.R, G: cell.Background.G, B: cell.Background.B}
+	}
+	if rendered {
+		luminance := cell.Luminance
+		model.Luminance = &luminance
+	}
+	return model
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -13,11 +14,15 @@ import (
 type httpMiddleWare http.HandlerFunc
 
 const (
-	CorrelationID = "correlationID"
-	Logger        = "logger"
-	Operation     = "operation"
+	CorrelationID  = "correlationID"
+	Logger         = "logger"
+	Operation      = "operation"
+	TerminalClient = "terminalClient"
 )
 
+// terminalUserAgents are the User-Agent prefixes of command line clients
+var terminalUserAgents = []string{"curl/", "wget/", "httpie/"}
+
 // WithLoggingAndTimeContext is a simple middleware to inject some request context info into a logrus entry
 func (w httpMiddleWare) WithLoggingContext(operationName string) httpMiddleWare {
 	return func(rw http.ResponseWriter, r *http.Request) {
@@ -61,3 +66,28 @@ func (w httpMiddleWare) WithDynamicTimeout(f func(r *http.Request) int) httpMidd
 		}
 	}
 }
+
+// WithPlainText is a middleware to flag requests from command line clients (curl, wget and HTTPie) that don't ask for any particular media type
+// handlers answer flagged requests with plain text instead of json, like wttr.in does
+func (w httpMiddleWare) WithPlainText() httpMiddleWare {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get(acceptHeader); accept != "" && accept != "*/*" {
+			w(rw, r)
+			return
+		}
+		userAgent := strings.ToLower(r.UserAgent())
+		for _, prefix := range terminalUserAgents {
+			if strings.HasPrefix(userAgent, prefix) {
+				r = r.WithContext(context.WithValue(r.Context(), TerminalClient, true))
+				break
+			}
+		}
+		w(rw, r)
+	}
+}
+
+// isTerminalClient returns whether WithPlainText flagged the request
+func isTerminalClient(r *http.Request) bool {
+	terminal, _ := r.Context().Value(TerminalClient).(bool)
+	return terminal
+}
@@ -100,3 +100,36 @@ func TestChainedMiddlewares(t *testing.T) {
 	handler := http.HandlerFunc(dummyHandler.WithLoggingContext("dummyHandler").WithTimeout(5000))
 	handler.ServeHTTP(rr, req)
 }
+
+func TestWithPlainText(t *testing.T) {
+	testCases := []struct {
+		userAgent string
+		accept    string
+		terminal  bool
+	}{
+		{"curl/7.68.0", "*/*", true},
+		{"Wget/1.20.3 (linux-gnu)", "", true},
+		{"HTTPie/2.2.0", "*/*", true},
+		// clients asking for a media type get what they asked for
+		{"curl/7.68.0", "application/json", false},
+		{"Mozilla/5.0 (X11; Linux x86_64)", "*/*", false},
+		{"", "", false},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest("GET", "/someurl", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", tc.userAgent)
+		req.Header.Set("Accept", tc.accept)
+
+		var terminal bool
+		var dummyHandler httpMiddleWare = func(writer http.ResponseWriter, request *http.Request) {
+			terminal = isTerminalClient(request)
+		}
+		handler := http.HandlerFunc(dummyHandler.WithPlainText())
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, tc.terminal, terminal, "%+v", tc)
+	}
+}
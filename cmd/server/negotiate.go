@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// acceptHeader is used to negotiate the format of GET /images/{id} when neither the extension nor formatParam pick one
+const acceptHeader = "Accept"
+
+// mediaTypes maps the media types clients can ask for to formats
+// wildcards (i.e */* or image/*) match the first listed type they cover, so */* keeps the json response
+var mediaTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{"application/json", formatJSON},
+	{"text/plain", formatText},
+	{"text/html", formatHTML},
+	{"image/png", formatPNG},
+	{"image/svg+xml", formatSVG},
+	{"application/x-ndjson", formatNDJSON},
+}
+
+// notAcceptableError is returned when the Accept header doesn't allow any of the formats
+type notAcceptableError struct {
+	accept string
+}
+
+func (e notAcceptableError) Error() string {
+	var supported []string
+	for _, m := range mediaTypes {
+		supported = append(supported, m.mediaType)
+	}
+	return fmt.Sprintf("none of the accepted media types %q are supported, supported types are %s", e.accept, strings.Join(supported, ", "))
+}
+
+// mediaRange is a single entry of an Accept header
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// matches returns how specifically the range matches the media type: 2 for an exact match, 1 for type/* and 0 for */*, -1 if it doesn't match
+func (m mediaRange) matches(mediaType string) int {
+	switch {
+	case m.mediaType == mediaType:
+		return 2
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*")):
+		return 1
+	}
+	return -1
+}
+
+// parseAccept parses an Accept header into its media ranges, skipping malformed entries
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		m := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), quality: 1}
+		if !strings.Contains(m.mediaType, "/") {
+			continue
+		}
+		for _, param := range params[1:] {
+			name, value := strings.TrimSpace(param), ""
+			if n := strings.Index(name, "="); n >= 0 {
+				name, value = strings.TrimSpace(name[:n]), strings.TrimSpace(name[n+1:])
+			}
+			if name != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			m.quality = q
+		}
+		ranges = append(ranges, m)
+	}
+	return ranges
+}
+
+// negotiateFormat picks the format for an Accept header
+// every format takes the quality of the most specific range matching it, the highest quality wins and ties go to the range listed first
+// an empty Accept header accepts anything
+// returns a notAcceptableError if none of the formats are acceptable
+func negotiateFormat(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, nil
+	}
+	ranges := parseAccept(accept)
+	best, bestQuality, bestPosition := "", 0.0, len(ranges)
+	for _, m := range mediaTypes {
+		quality, position, specificity := 0.0, len(ranges), -1
+		for n, r := range ranges {
+			if s := r.matches(m.mediaType); s > specificity {
+				quality, position, specificity = r.quality, n, s
+			}
+		}
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && position < bestPosition) {
+			best, bestQuality, bestPosition = m.format, quality, position
+		}
+	}
+	if best == "" {
+		return "", notAcceptableError{accept: accept}
+	}
+	return best, nil
+}
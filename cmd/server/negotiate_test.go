@@ -0,0 +1,43 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	testCases := []struct {
+		accept string
+		format string
+	}{
+		{"", formatJSON},
+		{"*/*", formatJSON},
+		{"application/json", formatJSON},
+		{"text/plain", formatText},
+		{"TEXT/PLAIN; charset=utf-8", formatText},
+		{"text/*", formatText},
+		{"image/*", formatPNG},
+		{"image/svg+xml", formatSVG},
+		// a browser's accept header
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8", formatHTML},
+		{"image/png;q=0.5, image/svg+xml", formatSVG},
+		// ties go to the range listed first
+		{"image/svg+xml, text/plain", formatSVG},
+		// the most specific range decides a type's quality
+		{"image/*, image/png;q=0", formatSVG},
+		{"*/*;q=0.1, text/plain", formatText},
+		{"application/xml, */*;q=0.5", formatJSON},
+	}
+	for _, tc := range testCases {
+		format, err := negotiateFormat(tc.accept)
+		assert.NoError(t, err, tc.accept)
+		assert.Equal(t, tc.format, format, tc.accept)
+	}
+}
+
+func TestNegotiateFormat_NotAcceptable(t *testing.T) {
+	for _, accept := range []string{"application/xml", "image/gif, video/*", "*/*;q=0", "text/plain;q=0, text/html;q=0, application/json;q=0", "garbage"} {
+		_, err := negotiateFormat(accept)
+		assert.IsType(t, notAcceptableError{}, err, accept)
+	}
+}
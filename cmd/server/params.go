@@ -0,0 +1,370 @@
+package server
+
+import (
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/export"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/gorilla/mux"
+	"image/color"
+	"net/http"
+	"strconv"
+)
+
+// conversion options can be passed in as either query parameters or headers
+// query parameters take precedence if both are set
+const (
+	widthParam     = "width"
+	heightParam    = "height"
+	scaleParam     = "scale"
+	colorParam     = "color"
+	rampParam      = "ramp"
+	invertParam    = "invert"
+	animateParam   = "animate"
+	modeParam      = "mode"
+	thresholdParam = "threshold"
+	ditherParam    = "dither"
+	adjustParam    = "adjust"
+	cropParam      = "crop"
+	rotateParam    = "rotate"
+	flipParam      = "flip"
+	orientParam    = "orient"
+	aspectParam    = "aspect"
+	alphaParam     = "alpha"
+	// backgroundParam is the color transparent pixels are composited onto with alpha=composite
+	backgroundParam     = "background"
+	alphaThresholdParam = "alphathreshold"
+	edgesParam          = "edges"
+	overlayParam        = "overlay"
+	emojiParam          = "emoji"
+	resampleParam       = "resample"
+	titleParam          = "title"
+	authorParam         = "author"
+	// colorDitherParam is how colors are snapped to the palette of color=16 and color=256, also when downsampling on GET
+	colorDitherParam = "colordither"
+)
+
+// adjustmentParams are the params for individual image adjustments, in the order they're applied after any adjustments listed in adjustParam
+var adjustmentParams = []image.AdjustmentKind{
+	image.AdjustLevels,
+	image.AdjustEqualize,
+	image.AdjustBrightness,
+	image.AdjustContrast,
+	image.AdjustGamma,
+	image.AdjustSharpen,
+}
+
+// formatParam selects the representation returned by GET /images/{id}
+const formatParam = "format"
+
+const (
+	// formatJSON is the default models.GetImageResponse body
+	formatJSON = "json"
+	// formatText is the raw monochrome ascii image as text/plain
+	formatText = "text"
+	// formatANSI is the raw ANSI colored image as text/plain, falling back to monochrome if the image has no colored rendition
+	formatANSI = "ansi"
+	// formatFrames is the models.GetImageFramesResponse body listing every frame of an animated image
+	formatFrames = "frames"
+	// formatMovie streams every frame of an animated image as ANSI text, redrawing each frame over the previous one
+	formatMovie = "movie"
+	// formatPNG is the image rasterized with a bitmap font as image/png
+	formatPNG = "png"
+	// formatSVG is the image as a standalone image/svg+xml document
+	formatSVG = "svg"
+	// formatHTML is the image as a standalone text/html document
+	formatHTML = "html"
+	// formatANS is the image as an ANSI art file with a SAUCE record, served as a .ans attachment
+	formatANS = "ans"
+	// formatCells is the models.GetImageCellsResponse body with the character and colors of every cell
+	formatCells = "cells"
+	// formatNDJSON streams the same cells as formatCells one models.CellsRow per line
+	formatNDJSON = "ndjson"
+	// formatMarkdown is the monochrome image as a fenced markdown code block
+	formatMarkdown = "markdown"
+	// formatSlack and formatDiscord are the monochrome image as a code block safe to paste into slack or discord
+	formatSlack   = "slack"
+	formatDiscord = "discord"
+	// formatIRC is the image colored with mIRC color codes as text/plain
+	formatIRC = "irc"
+)
+
+// plainParam overrides the command line client detection of WithPlainText
+// true answers with the raw ascii image, ansi with the ANSI colored image and false with the usual json
+const plainParam = "plain"
+
+// formatVar is the route variable holding the format for GET /images/{id}.{format}, it takes precedence over formatParam
+const formatVar = "format"
+
+// export options for the export formats (png, svg, html and ans), passed in the same way as conversion options
+const (
+	fontSizeParam   = "fontsize"
+	foregroundParam = "fg"
+	// bgParam is the color behind the characters, a separate param from backgroundParam which only applies to conversion
+	bgParam = "bg"
+	// colorParam=none draws exports in the foreground color only, ignoring the image's colors
+)
+
+func getRequestParam(r *http.Request, name string) string {
+	if value := r.URL.Query().Get(name); value != "" {
+		return value
+	}
+	return r.Header.Get(name)
+}
+
+// parseConversionOptions builds the image.ConversionOptions for a new image request
+// returns an image.InvalidInputError if any of the params are malformed or out of range
+func parseConversionOptions(r *http.Request) (image.ConversionOptions, error) {
+	var opts image.ConversionOptions
+	var err error
+	if opts.Width, err = parseIntParam(r, widthParam); err != nil {
+		return opts, err
+	}
+	if opts.Height, err = parseIntParam(r, heightParam); err != nil {
+		return opts, err
+	}
+	if opts.Scale, err = parseFloatParam(r, scaleParam); err != nil {
+		return opts, err
+	}
+	if opts.Color, err = image.ParseColorMode(getRequestParam(r, colorParam)); err != nil {
+		return opts, err
+	}
+	if opts.ColorDither, err = image.ParseDitherMode(getRequestParam(r, colorDitherParam)); err != nil {
+		return opts, err
+	}
+	if opts.Ramp, err = image.ParseRamp(getRequestParam(r, rampParam)); err != nil {
+		return opts, err
+	}
+	if opts.Invert, err = parseBoolParam(r, invertParam); err != nil {
+		return opts, err
+	}
+	if opts.Animate, err = parseBoolParam(r, animateParam); err != nil {
+		return opts, err
+	}
+	if opts.Mode, err = image.ParseRenderMode(getRequestParam(r, modeParam)); err != nil {
+		return opts, err
+	}
+	if opts.Threshold, err = parseOptionalFloatParam(r, thresholdParam); err != nil {
+		return opts, err
+	}
+	if opts.Dither, err = image.ParseDitherMode(getRequestParam(r, ditherParam)); err != nil {
+		return opts, err
+	}
+	if opts.Adjustments, err = parseAdjustments(r); err != nil {
+		return opts, err
+	}
+	if opts.Crop, err = image.ParseCrop(getRequestParam(r, cropParam)); err != nil {
+		return opts, err
+	}
+	if opts.Rotate, err = image.ParseRotation(getRequestParam(r, rotateParam)); err != nil {
+		return opts, err
+	}
+	if opts.Flip, err = image.ParseFlipMode(getRequestParam(r, flipParam)); err != nil {
+		return opts, err
+	}
+	if opts.CellAspect, err = parseFloatParam(r, aspectParam); err != nil {
+		return opts, err
+	}
+	if opts.Alpha, err = image.ParseAlphaMode(getRequestParam(r, alphaParam)); err != nil {
+		return opts, err
+	}
+	if background := getRequestParam(r, backgroundParam); background != "" {
+		if opts.Background, err = image.ParseColor(background); err != nil {
+			return opts, err
+		}
+	}
+	if opts.AlphaThreshold, err = parseOptionalFloatParam(r, alphaThresholdParam); err != nil {
+		return opts, err
+	}
+	if opts.EdgeDetector, err = image.ParseEdgeDetector(getRequestParam(r, edgesParam)); err != nil {
+		return opts, err
+	}
+	if opts.EdgeOverlay, err = parseBoolParam(r, overlayParam); err != nil {
+		return opts, err
+	}
+	if opts.Resample, err = image.ParseResampleMode(getRequestParam(r, resampleParam)); err != nil {
+		return opts, err
+	}
+	if opts.EmojiPalette, err = image.ParseEmojiPalette(getRequestParam(r, emojiParam)); err != nil {
+		return opts, err
+	}
+	opts.Title, opts.Author = getRequestParam(r, titleParam), getRequestParam(r, authorParam)
+	// exif orientation is corrected unless explicitly disabled
+	if getRequestParam(r, orientParam) != "" {
+		orient, err := parseBoolParam(r, orientParam)
+		if err != nil {
+			return opts, err
+		}
+		opts.IgnoreOrientation = !orient
+	}
+	return opts, opts.Validate()
+}
+
+// parseAdjustments combines the ordered adjustParam list with the individual adjustment params
+// levels and equalize are booleans, the others take their amount as the value
+func parseAdjustments(r *http.Request) ([]image.Adjustment, error) {
+	adjustments, err := image.ParseAdjustments(getRequestParam(r, adjustParam))
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range adjustmentParams {
+		value := getRequestParam(r, string(kind))
+		if value == "" {
+			continue
+		}
+		if kind == image.AdjustLevels || kind == image.AdjustEqualize {
+			enabled, err := parseBoolParam(r, string(kind))
+			if err != nil {
+				return nil, err
+			}
+			if enabled {
+				adjustments = append(adjustments, image.Adjustment{Kind: kind})
+			}
+			continue
+		}
+		adjustment, err := image.NewAdjustment(kind, value)
+		if err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	return adjustments, nil
+}
+
+// parseFormat returns the requested output format for GET /images/{id}
+// the extension takes precedence over formatParam, which takes precedence over the Accept header
+func parseFormat(r *http.Request) (string, error) {
+	format := mux.Vars(r)[formatVar]
+	if format == "" {
+		format = r.URL.Query().Get(formatParam)
+	}
+	if format == "" {
+		plain, err := parsePlainText(r, isTerminalClient(r))
+		if err != nil || plain != "" {
+			return plain, err
+		}
+	}
+	switch format {
+	case "":
+		return negotiateFormat(r.Header.Get(acceptHeader))
+	case formatJSON, formatText, formatANSI, formatFrames, formatMovie, formatPNG, formatSVG, formatHTML, formatANS, formatCells, formatNDJSON, formatMarkdown, formatSlack, formatDiscord, formatIRC:
+		return format, nil
+	default:
+		return "", image.NewInvalidInputError(fmt.Errorf("unknown format %q", format))
+	}
+}
+
+// parsePlainText returns the plain text format (formatText or formatANSI) to answer with, or "" to answer as usual
+// plainParam takes precedence over whether the request came from a command line client
+func parsePlainText(r *http.Request, terminal bool) (string, error) {
+	switch value := getRequestParam(r, plainParam); value {
+	case "":
+		if terminal {
+			return formatText, nil
+		}
+		return "", nil
+	case formatANSI:
+		return formatANSI, nil
+	default:
+		plain, err := parseBoolParam(r, plainParam)
+		if err != nil {
+			return "", image.NewInvalidInputError(fmt.Errorf("%s must be true, false or %s, got %q", plainParam, formatANSI, value))
+		}
+		if plain {
+			return formatText, nil
+		}
+		return "", nil
+	}
+}
+
+// parseExportOptions builds the export.Options for GET requests in the image formats
+func parseExportOptions(r *http.Request) (export.Options, error) {
+	var opts export.Options
+	var err error
+	if opts.FontSize, err = parseIntParam(r, fontSizeParam); err != nil {
+		return opts, err
+	}
+	if opts.Foreground, err = parseColorParam(r, foregroundParam); err != nil {
+		return opts, err
+	}
+	if opts.Background, err = parseColorParam(r, bgParam); err != nil {
+		return opts, err
+	}
+	// other color modes are handled by parsePalette
+	if getRequestParam(r, colorParam) == "none" {
+		opts.Monochrome = true
+	}
+	return opts, opts.Validate()
+}
+
+// parsePalette returns the color mode and dither mode a stored image's colored renditions are downsampled to on GET
+// ColorNone leaves the image's colors as they are
+func parsePalette(r *http.Request) (image.ColorMode, image.DitherMode, error) {
+	mode, err := image.ParseColorMode(getRequestParam(r, colorParam))
+	if err != nil {
+		return mode, image.DitherNone, err
+	}
+	dither, err := image.ParseDitherMode(getRequestParam(r, colorDitherParam))
+	return mode, dither, err
+}
+
+func parseColorParam(r *http.Request, name string) (*color.NRGBA, error) {
+	value := getRequestParam(r, name)
+	if value == "" {
+		return nil, nil
+	}
+	c, err := image.ParseColor(value)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func parseIntParam(r *http.Request, name string) (int, error) {
+	value := getRequestParam(r, name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, image.NewInvalidInputError(fmt.Errorf("%s must be a positive integer, got %q", name, value))
+	}
+	return n, nil
+}
+
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := getRequestParam(r, name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, image.NewInvalidInputError(fmt.Errorf("%s must be true or false, got %q", name, value))
+	}
+	return b, nil
+}
+
+func parseFloatParam(r *http.Request, name string) (float64, error) {
+	value := getRequestParam(r, name)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		return 0, image.NewInvalidInputError(fmt.Errorf("%s must be a positive number, got %q", name, value))
+	}
+	return f, nil
+}
+
+// parseOptionalFloatParam is nil if the param isn't set, so options where 0 is meaningful can tell it apart from their default
+// the range is left to ConversionOptions.Validate
+func parseOptionalFloatParam(r *http.Request, name string) (*float64, error) {
+	value := getRequestParam(r, name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, image.NewInvalidInputError(fmt.Errorf("%s must be a number, got %q", name, value))
+	}
+	return &f, nil
+}
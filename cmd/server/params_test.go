@@ -0,0 +1,177 @@
+package server
+
+import (
+	"github.com/eriksywu/ascii/pkg/export"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseConversionOptions(t *testing.T) {
+	req, err := http.NewRequest("POST", "/images?width=80&scale=0.5&color=256&ramp=simple&invert=true&mode=braille&threshold=0.3&dither=atkinson", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("height", "40")
+	// query parameters take precedence over headers
+	req.Header.Set("width", "10")
+
+	opts, err := parseConversionOptions(req)
+
+	assert.NoError(t, err)
+	threshold := 0.3
+	assert.Equal(t, image.ConversionOptions{Mode: image.ModeBraille, Width: 80, Height: 40, Scale: 0.5, Color: image.Color256, Ramp: " .:-=+*#%@", Invert: true, Threshold: &threshold, Dither: image.DitherAtkinson}, opts)
+}
+
+func TestParseConversionOptions_ZeroThreshold(t *testing.T) {
+	req, err := http.NewRequest("POST", "/images?mode=braille&threshold=0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseConversionOptions(req)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, opts.Threshold) {
+		assert.Equal(t, 0.0, *opts.Threshold)
+	}
+}
+
+func TestParseConversionOptions_Adjustments(t *testing.T) {
+	req, err := http.NewRequest("POST", "/images?adjust=sharpen:2,brightness:0.1&gamma=1.4&brightness=-0.5&equalize=true&levels=false", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseConversionOptions(req)
+
+	assert.NoError(t, err)
+	// the adjust list comes first, followed by the individual params in a fixed order
+	assert.Equal(t, []image.Adjustment{
+		{Kind: image.AdjustSharpen, Amount: 2},
+		{Kind: image.AdjustBrightness, Amount: 0.1},
+		{Kind: image.AdjustEqualize},
+		{Kind: image.AdjustBrightness, Amount: -0.5},
+		{Kind: image.AdjustGamma, Amount: 1.4},
+	}, opts.Adjustments)
+}
+
+func TestParseConversionOptions_Geometry(t *testing.T) {
+	req, err := http.NewRequest("POST", "/images?crop=10%25,10%25,80%25,80%25&rotate=270&flip=vertical&orient=false&aspect=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseConversionOptions(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, image.Crop{X: 10, Y: 10, Width: 80, Height: 80, Percent: true}, opts.Crop)
+	assert.Equal(t, 270, opts.Rotate)
+	assert.Equal(t, image.FlipVertical, opts.Flip)
+	assert.True(t, opts.IgnoreOrientation)
+	assert.Equal(t, 1.0, opts.CellAspect)
+}
+
+func TestParseConversionOptions_Alpha(t *testing.T) {
+	req, err := http.NewRequest("POST", "/images?alpha=composite&background=%23ffffff&alphathreshold=0.25", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseConversionOptions(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, image.AlphaComposite, opts.Alpha)
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, opts.Background)
+	if assert.NotNil(t, opts.AlphaThreshold) {
+		assert.Equal(t, 0.25, *opts.AlphaThreshold)
+	}
+}
+
+func TestParseConversionOptions_Invalid(t *testing.T) {
+	for _, query := range []string{"width=abc", "height=0", "scale=-1", "scale=100", "width=100000", "color=rainbow", "invert=maybe", "ramp=x", "mode=sixel", "threshold=2", "threshold=-0.1", "threshold=abc", "dither=noise", "adjust=blur", "gamma=0", "brightness=abc", "equalize=maybe", "crop=1,2,3", "rotate=45", "flip=diagonal", "orient=sometimes", "aspect=0", "aspect=10", "alpha=ignore", "background=purple", "alphathreshold=2", "alphathreshold=-1", "edges=prewitt", "overlay=sometimes", "resample=bicubic", "title=" + strings.Repeat("x", 36), "author=" + strings.Repeat("x", 21)} {
+		req, err := http.NewRequest("POST", "/images?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = parseConversionOptions(req)
+
+		assert.Error(t, err, query)
+		_, isInputError := err.(image.InvalidInputError)
+		assert.True(t, isInputError, query)
+	}
+}
+
+func TestParseExportOptions(t *testing.T) {
+	req, err := http.NewRequest("GET", "/images/some-id.png?fontsize=20&fg=%23ff0000&color=none", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("bg", "white")
+
+	opts, err := parseExportOptions(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, export.Options{
+		FontSize:   20,
+		Foreground: &color.NRGBA{R: 255, A: 255},
+		Background: &color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+		Monochrome: true,
+	}, opts)
+}
+
+func TestParseExportOptions_Invalid(t *testing.T) {
+	for _, query := range []string{"fontsize=2", "fontsize=100", "fontsize=abc", "fg=purple", "bg=%2312"} {
+		req, err := http.NewRequest("GET", "/images/some-id.png?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = parseExportOptions(req)
+
+		assert.Error(t, err, query)
+		_, isInputError := err.(image.InvalidInputError)
+		assert.True(t, isInputError, query)
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	req, err := http.NewRequest("GET", "/images/some-id?color=16&colordither=floyd-steinberg", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode, dither, err := parsePalette(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, image.Color16, mode)
+	assert.Equal(t, image.DitherFloydSteinberg, dither)
+
+	for _, query := range []string{"color=512", "colordither=random"} {
+		req, err := http.NewRequest("GET", "/images/some-id?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = parsePalette(req)
+
+		_, isInputError := err.(image.InvalidInputError)
+		assert.True(t, isInputError, query)
+	}
+}
+
+func TestParsePlainText_Invalid(t *testing.T) {
+	req, err := http.NewRequest("GET", "/images/some-id?plain=maybe", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parsePlainText(req, true)
+
+	_, isInputError := err.(image.InvalidInputError)
+	assert.True(t, isInputError)
+}
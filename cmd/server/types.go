@@ -2,13 +2,15 @@ package server
 
 import (
 	"context"
+	"github.com/eriksywu/ascii/pkg/image"
 	"github.com/google/uuid"
 	"io"
 )
 
 type ASCIIImageService interface {
-	GetASCIIImage(context.Context, uuid.UUID) (bool, []byte, error)
-	NewASCIIImageAsync(context.Context, io.ReadCloser) (*uuid.UUID, error)
-	NewASCIIImageSync(context.Context, io.ReadCloser) (*uuid.UUID, error)
+	GetASCIIImage(context.Context, uuid.UUID) (bool, *image.ASCIIImage, error)
+	GetASCIIImageCells(context.Context, uuid.UUID) (bool, *image.ASCIIImage, error)
+	NewASCIIImageAsync(context.Context, io.ReadCloser, image.ConversionOptions) (*uuid.UUID, string, error)
+	NewASCIIImageSync(context.Context, io.ReadCloser, image.ConversionOptions) (*uuid.UUID, string, error)
 	GetImageList(context.Context) ([]uuid.UUID, error)
 }
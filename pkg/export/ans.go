@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"image/color"
+	"io"
+	"time"
+)
+
+// ANSI art's default colors, light gray on black
+const (
+	ansDefaultForeground = 7
+	ansDefaultBackground = 0
+)
+
+// ansFont is the font ANSI art is drawn in
+const ansFont = "IBM VGA"
+
+// now is the date written into SAUCE records
+var now = time.Now
+
+// brailleShades stand in for braille characters, which code page 437 doesn't have, by how many of the 8 dots are set
+var brailleShades = [9]rune{' ', '░', '░', '▒', '▒', '▓', '▓', '█', '█'}
+
+// WriteANS writes the grid as an ANSI art (.ans) file: code page 437 text colored with the 16 VGA colors, followed by a SAUCE record
+// colors are matched to the nearest VGA color, bright backgrounds rely on iCE colors which the SAUCE record turns on
+// characters missing from code page 437 are replaced, braille by a shade with about as many dots set and anything else by '?'
+// returns an image.InvalidInputError if the grid is empty
+func WriteANS(w io.Writer, grid image.Grid, opts Options) error {
+	if columns(grid) == 0 {
+		return image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	var buf bytes.Buffer
+	for _, row := range grid {
+		// every line starts out in the default colors so lines can be viewed on their own
+		foreground, background := -1, -1
+		for _, cell := range row {
+			f, b := ansDefaultForeground, ansDefaultBackground
+			if !opts.Monochrome {
+				if cell.Color.A != 0 {
+					f = nearestVGAColor(cell.Color)
+				}
+				if cell.Background != nil {
+					b = nearestVGAColor(*cell.Background)
+				}
+			}
+			if f != foreground || b != background {
+				buf.WriteString(ansEscape(f, b))
+				foreground, background = f, b
+			}
+			buf.WriteByte(ansChar(cell.Char))
+		}
+		buf.WriteString("\x1b[0m\r\n")
+	}
+	sauce := image.SAUCE{
+		Title:    opts.Title,
+		Author:   opts.Author,
+		Date:     now(),
+		FileSize: uint32(buf.Len()),
+		Width:    uint16(columns(grid)),
+		Height:   uint16(len(grid)),
+		Flags:    image.SAUCEFlagICEColors | image.SAUCEFlag8PxFont | image.SAUCEFlagLegacyAspect,
+		Font:     ansFont,
+	}
+	record, err := sauce.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(0x1a)
+	buf.Write(record)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// ansEscape resets the attributes and sets the foreground and background to VGA colors
+// bright foregrounds are bold and bright backgrounds blink, which selects a bright background with iCE colors
+func ansEscape(foreground, background int) string {
+	escape := "\x1b[0"
+	if foreground >= 8 {
+		escape += ";1"
+	}
+	if background >= 8 {
+		escape += ";5"
+	}
+	return escape + fmt.Sprintf(";%d;%dm", 30+foreground%8, 40+background%8)
+}
+
+func ansChar(r rune) byte {
+	if b, k := image.EncodeCP437(r); k {
+		return b
+	}
+	if r >= '⠀' && r <= '⣿' {
+		dots := 0
+		for pattern := r - '⠀'; pattern > 0; pattern >>= 1 {
+			dots += int(pattern & 1)
+		}
+		b, _ := image.EncodeCP437(brailleShades[dots])
+		return b
+	}
+	return '?'
+}
+
+// vgaPalette matches colors to the 16 VGA colors
+var vgaPalette = image.NewPalette(image.VGAPalette[:]...)
+
+// nearestVGAColor returns the index of the VGA color closest to c
+func nearestVGAColor(c color.NRGBA) int {
+	return vgaPalette.Nearest(c)
+}
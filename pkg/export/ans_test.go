@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestWriteANS(t *testing.T) {
+	now = func() time.Time { return time.Date(2020, 12, 24, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	// close to bright yellow and blue
+	yellow := color.NRGBA{R: 250, G: 240, B: 100, A: 255}
+	blue := color.NRGBA{B: 160, A: 255}
+	grid := image.Grid{
+		{{Char: '▀', Color: yellow, Background: &blue}, {Char: '⣿', Color: yellow}, {Char: '→'}},
+		{{Char: '@'}},
+	}
+	var buf bytes.Buffer
+
+	err := WriteANS(&buf, grid, Options{Title: "title", Author: "author"})
+
+	assert.NoError(t, err)
+	art := "\x1b[0;1;33;44m\xdf\x1b[0;1;33;40m\xdb\x1b[0;37;40m?\x1b[0m\r\n\x1b[0;37;40m@\x1b[0m\r\n"
+	assert.Equal(t, art+"\x1a", buf.String()[:len(art)+1])
+
+	parsed, sauce := image.ParseANS(buf.Bytes())
+	assert.Equal(t, image.SAUCE{
+		Title:    "title",
+		Author:   "author",
+		Date:     time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC),
+		FileSize: uint32(len(art)),
+		Width:    3,
+		Height:   2,
+		Flags:    image.SAUCEFlagICEColors | image.SAUCEFlag8PxFont | image.SAUCEFlagLegacyAspect,
+		Font:     "IBM VGA",
+	}, *sauce)
+	vgaBlue := image.VGAPalette[4]
+	assert.Equal(t, image.Grid{
+		{{Char: '▀', Color: image.VGAPalette[11], Background: &vgaBlue}, {Char: '█', Color: image.VGAPalette[11]}, {Char: '?', Color: image.VGAPalette[7]}},
+		{{Char: '@', Color: image.VGAPalette[7]}},
+	}, parsed)
+}
+
+func TestWriteANS_BrightBackground(t *testing.T) {
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	var buf bytes.Buffer
+
+	err := WriteANS(&buf, image.Grid{{{Char: ' ', Background: &white}}}, Options{})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "\x1b[0;5;37;47m ")
+
+	buf.Reset()
+	err = WriteANS(&buf, image.Grid{{{Char: ' ', Background: &white}}}, Options{Monochrome: true})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "\x1b[0;37;40m ")
+}
@@ -0,0 +1,209 @@
+package export
+
+import (
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"image/color"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// practical line limits of the places images get pasted, wider images are scaled down to fit
+const (
+	// MarkdownMaxWidth fits a fenced code block on github without scrolling
+	MarkdownMaxWidth = 120
+	// SlackMaxWidth fits a code block in slack's desktop client without wrapping
+	SlackMaxWidth = 80
+	// DiscordMaxWidth fits a code block in discord's narrower message column without wrapping
+	DiscordMaxWidth = 60
+	// IRCMaxWidth is the width of a classic terminal irc client
+	IRCMaxWidth = 80
+)
+
+// practical message size limits, images that don't fit are scaled down further until they do
+const (
+	// SlackMaxLength is the number of characters slack truncates messages at
+	SlackMaxLength = 40000
+	// DiscordMaxLength is the number of characters a discord message can have, including the code block fences
+	DiscordMaxLength = 2000
+	// IRCMaxLineBytes is the longest line, color codes and all, that fits in a single irc message
+	// a message is at most 512 bytes, which also holds the sender's prefix, the PRIVMSG command, the target and the CRLF
+	IRCMaxLineBytes = 400
+)
+
+// chatReplacements swaps the characters slack and discord turn into formatting for ones they leave alone
+var chatReplacements = strings.NewReplacer("`", "'", "*", "+", "_", "-", "~", "-")
+
+// mIRCColors are the 16 standard mIRC colors, in color code order
+var mIRCColors = [16]color.NRGBA{
+	{255, 255, 255, 255}, {0, 0, 0, 255}, {0, 0, 127, 255}, {0, 147, 0, 255},
+	{255, 0, 0, 255}, {127, 0, 0, 255}, {156, 0, 156, 255}, {252, 127, 0, 255},
+	{255, 255, 0, 255}, {0, 252, 0, 255}, {0, 147, 147, 255}, {0, 255, 255, 255},
+	{0, 0, 252, 255}, {255, 0, 255, 255}, {127, 127, 127, 255}, {210, 210, 210, 255},
+}
+
+var mIRCPalette = image.NewPalette(mIRCColors[:]...)
+
+// mIRC formatting codes
+const (
+	ircColor = "\x03"
+	ircBold  = "\x02"
+	ircReset = "\x0f"
+	// ircDefaultForeground is used for cells that only have a background, since the color code can't set a background alone
+	ircDefaultForeground = 15
+)
+
+// WriteMarkdown writes the grid as a fenced markdown code block, scaled down to MarkdownMaxWidth
+// the fence is longer than any run of backticks in the image so the image can't close it
+func WriteMarkdown(w io.Writer, grid image.Grid, opts Options) error {
+	text, err := plainText(grid, MarkdownMaxWidth)
+	if err != nil {
+		return err
+	}
+	fence := strings.Repeat("`", longestRun(text, '`')+1)
+	if len(fence) < 3 {
+		fence = "```"
+	}
+	_, err = io.WriteString(w, fence+"text\n"+text+fence+"\n")
+	return err
+}
+
+// WriteSlack writes the grid as a slack code block, scaled down to SlackMaxWidth and SlackMaxLength
+func WriteSlack(w io.Writer, grid image.Grid, opts Options) error {
+	return writeChat(w, grid, SlackMaxWidth, SlackMaxLength)
+}
+
+// WriteDiscord writes the grid as a discord code block, scaled down to DiscordMaxWidth and DiscordMaxLength so it can be sent as a single message
+func WriteDiscord(w io.Writer, grid image.Grid, opts Options) error {
+	return writeChat(w, grid, DiscordMaxWidth, DiscordMaxLength)
+}
+
+// writeChat writes the grid as a code block with the characters chat clients format (backticks, *, _ and ~) replaced
+// the code block keeps chat clients from collapsing runs of spaces
+func writeChat(w io.Writer, grid image.Grid, maxWidth, maxLength int) error {
+	if columns(grid) == 0 {
+		return image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	var message string
+	shrinkToFit(grid, maxWidth, func(scaled image.Grid) bool {
+		message = "```\n" + chatReplacements.Replace(scaled.String()) + "```\n"
+		return utf8.RuneCountInString(message) <= maxLength
+	})
+	_, err := io.WriteString(w, message)
+	return err
+}
+
+// WriteIRC writes the grid as text colored with mIRC color codes, scaled down to IRCMaxWidth and further until every line fits in IRCMaxLineBytes
+// colors are matched to the nearest of the 16 standard mIRC colors and every line ends with a reset so it can be sent as its own message
+func WriteIRC(w io.Writer, grid image.Grid, opts Options) error {
+	if columns(grid) == 0 {
+		return image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	var lines []string
+	shrinkToFit(grid, IRCMaxWidth, func(scaled image.Grid) bool {
+		lines = make([]string, len(scaled))
+		for y, row := range scaled {
+			if lines[y] = ircLine(row, opts); len(lines[y]) > IRCMaxLineBytes {
+				return false
+			}
+		}
+		return true
+	})
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// ircLine is a row of the image colored with mIRC color codes
+func ircLine(row []image.Cell, opts Options) string {
+	var sb strings.Builder
+	colored, hasBackground := false, false
+	for _, r := range runs(row, opts) {
+		// a color code without a background keeps the previous background, so backgrounds can only be cleared with a reset
+		if colored && (r.foreground == nil && r.background == nil || hasBackground && r.background == nil) {
+			sb.WriteString(ircReset)
+			colored, hasBackground = false, false
+		}
+		switch {
+		case r.foreground == nil && r.background == nil:
+		case r.background == nil:
+			fmt.Fprintf(&sb, "%s%02d", ircColor, mIRCPalette.Nearest(*r.foreground))
+			colored = true
+			// a leading comma would be read as the start of a background, toggling bold twice separates them
+			if strings.HasPrefix(r.text, ",") {
+				sb.WriteString(ircBold + ircBold)
+			}
+		default:
+			foreground := ircDefaultForeground
+			if r.foreground != nil {
+				foreground = mIRCPalette.Nearest(*r.foreground)
+			}
+			fmt.Fprintf(&sb, "%s%02d,%02d", ircColor, foreground, mIRCPalette.Nearest(*r.background))
+			colored, hasBackground = true, true
+		}
+		sb.WriteString(r.text)
+	}
+	if colored {
+		sb.WriteString(ircReset)
+	}
+	return sb.String()
+}
+
+// plainText is the monochrome text of the grid scaled down to maxWidth
+func plainText(grid image.Grid, maxWidth int) (string, error) {
+	if columns(grid) == 0 {
+		return "", image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	return fitWidth(grid, maxWidth).String(), nil
+}
+
+// fitWidth scales the grid down to maxWidth columns, keeping its aspect ratio, by picking the nearest cell
+// grids that already fit are returned as is
+func fitWidth(grid image.Grid, maxWidth int) image.Grid {
+	width := columns(grid)
+	if width <= maxWidth {
+		return grid
+	}
+	height := len(grid) * maxWidth / width
+	if height < 1 {
+		height = 1
+	}
+	scaled := make(image.Grid, height)
+	for y := range scaled {
+		row := grid[y*len(grid)/height]
+		scaled[y] = make([]image.Cell, maxWidth)
+		for x := range scaled[y] {
+			scaled[y][x] = image.Cell{Char: ' '}
+			if n := x * width / maxWidth; n < len(row) {
+				scaled[y][x] = row[n]
+			}
+		}
+	}
+	return scaled
+}
+
+// shrinkToFit scales the grid down to maxWidth columns and then a column at a time until fits accepts it, or it's a single column wide
+func shrinkToFit(grid image.Grid, maxWidth int, fits func(image.Grid) bool) {
+	width := columns(grid)
+	if width > maxWidth {
+		width = maxWidth
+	}
+	for !fits(fitWidth(grid, width)) && width > 1 {
+		width--
+	}
+}
+
+func longestRun(s string, r rune) int {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c != r {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteMarkdown(&buf, image.ParseANSI("@ `\n``` #\n"), Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "````text\n@ `\n``` #\n````\n", buf.String())
+}
+
+func TestWriteChat(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteSlack(&buf, image.ParseANSI("*_`~\n@  @\n"), Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "```\n+-'-\n@  @\n```\n", buf.String())
+}
+
+func TestWriteChat_ClampsWidth(t *testing.T) {
+	wide := strings.Repeat(strings.Repeat("#", 100)+"\n", 10)
+	for name, test := range map[string]struct {
+		write    func(*bytes.Buffer) error
+		maxWidth int
+	}{
+		"markdown": {func(buf *bytes.Buffer) error { return WriteMarkdown(buf, image.ParseANSI(wide), Options{}) }, 100},
+		"slack":    {func(buf *bytes.Buffer) error { return WriteSlack(buf, image.ParseANSI(wide), Options{}) }, SlackMaxWidth},
+		"discord":  {func(buf *bytes.Buffer) error { return WriteDiscord(buf, image.ParseANSI(wide), Options{}) }, DiscordMaxWidth},
+	} {
+		var buf bytes.Buffer
+
+		err := test.write(&buf)
+
+		assert.NoError(t, err, name)
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		// the fences plus the image scaled down with its aspect ratio
+		assert.Len(t, lines, 2+10*test.maxWidth/100, name)
+		assert.Equal(t, strings.Repeat("#", test.maxWidth), lines[1], name)
+	}
+}
+
+func TestWriteIRC(t *testing.T) {
+	red := color.NRGBA{R: 250, A: 255}
+	blue := color.NRGBA{B: 130, A: 255}
+	grid := image.Grid{
+		{{Char: '▀', Color: red, Background: &blue}, {Char: '#', Color: red}, {Char: ',', Color: blue}, {Char: ' ', Transparent: true}, {Char: '@'}},
+		{{Char: '@'}, {Char: ' ', Background: &blue}},
+	}
+	var buf bytes.Buffer
+
+	err := WriteIRC(&buf, grid, Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "\x0304,02▀\x0f\x0304#\x0302\x02\x02,\x0f @\n@\x0315,02 \x0f\n", buf.String())
+
+	buf.Reset()
+	err = WriteIRC(&buf, grid, Options{Monochrome: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "▀#, @\n@ \n", buf.String())
+}
+
+func TestWriteDiscord_FitsMessageLimit(t *testing.T) {
+	tall := strings.Repeat(strings.Repeat("#", 60)+"\n", 100)
+	var buf bytes.Buffer
+
+	err := WriteDiscord(&buf, image.ParseANSI(tall), Options{})
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, utf8.RuneCountInString(buf.String()), DiscordMaxLength)
+	assert.True(t, strings.HasPrefix(buf.String(), "```\n#"))
+}
+
+func TestWriteIRC_FitsLineLimit(t *testing.T) {
+	// every cell a different color, so every cell needs its own color code
+	grid := make(image.Grid, 4)
+	for y := range grid {
+		for x := 0; x < 80; x++ {
+			background := color.NRGBA{B: uint8(x % 2 * 255), A: 255}
+			grid[y] = append(grid[y], image.Cell{Char: '⣿', Color: color.NRGBA{R: uint8(x * 3), G: uint8(255 - x*3), A: 255}, Background: &background})
+		}
+	}
+	var buf bytes.Buffer
+
+	err := WriteIRC(&buf, grid, Options{})
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), IRCMaxLineBytes)
+		assert.NotEmpty(t, line)
+	}
+}
+
+func TestWriteIRC_Empty(t *testing.T) {
+	err := WriteIRC(&bytes.Buffer{}, image.Grid{}, Options{})
+
+	assert.True(t, errors.As(err, &image.InvalidInputError{}))
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"html"
+	"io"
+	"strings"
+)
+
+// WriteHTML writes the grid as a standalone HTML document holding a single <pre> block
+// runs of same colored characters are wrapped in <span>s styled with their colors
+// returns an image.InvalidInputError if the grid is empty
+func WriteHTML(w io.Writer, grid image.Grid, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	if columns(grid) == 0 {
+		return image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+
+	var sb strings.Builder
+	title := opts.Title
+	if title == "" {
+		title = "ASCII image"
+	}
+	fmt.Fprintf(&sb, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n", html.EscapeString(title))
+	fmt.Fprintf(&sb, "body { margin: 0; background: %s; }\n", image.FormatColor(*opts.Background))
+	fmt.Fprintf(&sb, "pre { margin: 0; color: %s; font-family: monospace; font-size: %dpx; line-height: 1; }\n", image.FormatColor(*opts.Foreground), opts.FontSize)
+	sb.WriteString("</style>\n</head>\n<body>\n<pre>")
+	for _, row := range grid {
+		for _, r := range runs(row, opts) {
+			var styles []string
+			if r.foreground != nil {
+				styles = append(styles, "color: "+image.FormatColor(*r.foreground))
+			}
+			if r.background != nil {
+				styles = append(styles, "background: "+image.FormatColor(*r.background))
+			}
+			if len(styles) == 0 {
+				sb.WriteString(html.EscapeString(r.text))
+				continue
+			}
+			fmt.Fprintf(&sb, "<span style=\"%s\">%s</span>", strings.Join(styles, "; "), html.EscapeString(r.text))
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
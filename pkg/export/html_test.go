@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	grid := image.Grid{
+		{{Char: '<', Color: red}, {Char: '>', Color: red}, {Char: '▀', Color: red, Background: &blue}},
+		{{Char: '&'}, {Char: '\''}},
+	}
+	var buf bytes.Buffer
+
+	err := WriteHTML(&buf, grid, Options{FontSize: 10, Background: &blue})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ASCII image</title>
+<style>
+body { margin: 0; background: #0000ff; }
+pre { margin: 0; color: #ffffff; font-family: monospace; font-size: 10px; line-height: 1; }
+</style>
+</head>
+<body>
+<pre><span style="color: #ff0000">&lt;&gt;</span><span style="color: #ff0000; background: #0000ff">▀</span>
+&amp;&#39;
+</pre>
+</body>
+</html>
+`, buf.String())
+}
+
+func TestWriteHTML_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteHTML(&buf, image.Grid{}, Options{})
+	assert.True(t, errors.As(err, &image.InvalidInputError{}))
+
+	err = WriteHTML(&buf, image.Grid{{{Char: 'a'}}}, Options{FontSize: 1})
+	assert.True(t, errors.As(err, &image.InvalidInputError{}))
+}
+
+func TestWriteHTML_Title(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteHTML(&buf, image.Grid{{{Char: 'a'}}}, Options{Title: "<script>"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "<title>&lt;script&gt;</title>")
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"image/color"
+)
+
+const (
+	// DefaultFontSize is the height of a character cell in pixels, the native size of the embedded bitmap font
+	DefaultFontSize = 13
+	MinFontSize     = 6
+	MaxFontSize     = 64
+	// MaxPixels caps the size of rasterized images, large images need a smaller font size
+	MaxPixels = 40000000
+)
+
+var (
+	DefaultForeground = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	DefaultBackground = color.NRGBA{A: 255}
+)
+
+// Options controls how an image is drawn by the exporters
+// the zero value draws white characters on black at the font's native size, in the image's own colors if it has any
+type Options struct {
+	// FontSize is the height of a character cell in pixels, cells are as wide as the font's aspect ratio allows
+	FontSize int
+	// Foreground colors characters without a color of their own, DefaultForeground if unset
+	Foreground *color.NRGBA
+	// Background fills the image behind the characters, DefaultBackground if unset
+	Background *color.NRGBA
+	// Monochrome draws every character with Foreground and ignores the image's colors
+	Monochrome bool
+	// Title and Author are written into formats that carry metadata (i.e the SAUCE record of .ans files)
+	Title  string
+	Author string
+}
+
+// Validate returns an image.InvalidInputError if any option is out of range
+func (o Options) Validate() error {
+	if o.FontSize != 0 && (o.FontSize < MinFontSize || o.FontSize > MaxFontSize) {
+		return image.NewInvalidInputError(fmt.Errorf("font size must be between %d and %d, got %d", MinFontSize, MaxFontSize, o.FontSize))
+	}
+	return nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.FontSize == 0 {
+		o.FontSize = DefaultFontSize
+	}
+	if o.Foreground == nil {
+		foreground := DefaultForeground
+		o.Foreground = &foreground
+	}
+	if o.Background == nil {
+		background := DefaultBackground
+		o.Background = &background
+	}
+	return o
+}
+
+// foreground is the color a cell's character is drawn in
+func (o Options) foreground(cell image.Cell) color.NRGBA {
+	if o.Monochrome || cell.Color.A == 0 {
+		return *o.Foreground
+	}
+	return cell.Color
+}
+
+// background is the color behind a cell's character, nil if the cell has no background of its own
+func (o Options) background(cell image.Cell) *color.NRGBA {
+	if o.Monochrome {
+		return nil
+	}
+	return cell.Background
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	stdimage "image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// the embedded bitmap font, every glyph is drawn into a fontWidth x fontHeight cell before being scaled to the cell size
+var (
+	font       = basicfont.Face7x13
+	fontWidth  = font.Advance
+	fontHeight = font.Height
+)
+
+// block element and braille characters are drawn procedurally since the bitmap font doesn't have them
+const (
+	fullBlock      = '█'
+	upperHalfBlock = '▀'
+	lowerHalfBlock = '▄'
+	lightShade     = '░'
+	mediumShade    = '▒'
+	darkShade      = '▓'
+	brailleBlank   = '⠀'
+	brailleLast    = '⣿'
+)
+
+// brailleDots are the offsets of each braille dot within the 2x4 dot cell, in the order of their bits
+var brailleDots = [8]stdimage.Point{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {0, 3}, {1, 3}}
+
+// WritePNG rasterizes the grid with the embedded monospace bitmap font and writes it as a PNG
+func WritePNG(w io.Writer, grid image.Grid, opts Options) error {
+	m, err := Rasterize(grid, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, m)
+}
+
+// Rasterize draws the grid with the embedded monospace bitmap font, one character cell per grid cell
+// returns an image.InvalidInputError if the grid is empty or the result would be larger than MaxPixels
+func Rasterize(grid image.Grid, opts Options) (*stdimage.NRGBA, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	cellWidth, cellHeight := cellSize(opts.FontSize)
+	cols := columns(grid)
+	if cols == 0 {
+		return nil, image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	if cols*cellWidth*len(grid)*cellHeight > MaxPixels {
+		return nil, image.NewInvalidInputError(fmt.Errorf("a %dx%d image at font size %d is larger than %d pixels, use a smaller font size", cols, len(grid), opts.FontSize, MaxPixels))
+	}
+
+	m := stdimage.NewNRGBA(stdimage.Rect(0, 0, cols*cellWidth, len(grid)*cellHeight))
+	draw.Draw(m, m.Bounds(), stdimage.NewUniform(*opts.Background), stdimage.Point{}, draw.Src)
+	glyphs := newGlyphCache(cellWidth, cellHeight)
+	for y, row := range grid {
+		for x, cell := range row {
+			cellRect := stdimage.Rect(x*cellWidth, y*cellHeight, (x+1)*cellWidth, (y+1)*cellHeight)
+			if background := opts.background(cell); background != nil {
+				draw.Draw(m, cellRect, stdimage.NewUniform(*background), stdimage.Point{}, draw.Src)
+			}
+			if mask := glyphs.get(cell.Char); mask != nil {
+				draw.DrawMask(m, cellRect, stdimage.NewUniform(opts.foreground(cell)), stdimage.Point{}, mask, stdimage.Point{}, draw.Over)
+			}
+		}
+	}
+	return m, nil
+}
+
+// cellSize is the size in pixels of a character cell for the font size, keeping the font's aspect ratio
+func cellSize(fontSize int) (int, int) {
+	width := int(math.Round(float64(fontSize) * float64(fontWidth) / float64(fontHeight)))
+	if width < 1 {
+		width = 1
+	}
+	return width, fontSize
+}
+
+// glyphCache holds the cell sized alpha mask of every character drawn so far
+type glyphCache struct {
+	width, height int
+	masks         map[rune]*stdimage.Alpha
+}
+
+func newGlyphCache(width, height int) *glyphCache {
+	return &glyphCache{width: width, height: height, masks: map[rune]*stdimage.Alpha{}}
+}
+
+// get returns the mask of the character, nil for characters that draw nothing
+func (g *glyphCache) get(r rune) *stdimage.Alpha {
+	if mask, k := g.masks[r]; k {
+		return mask
+	}
+	mask := g.draw(r)
+	g.masks[r] = mask
+	return mask
+}
+
+func (g *glyphCache) draw(r rune) *stdimage.Alpha {
+	bounds := stdimage.Rect(0, 0, g.width, g.height)
+	mask := stdimage.NewAlpha(bounds)
+	switch {
+	case r == ' ' || r == brailleBlank || r == 0:
+		return nil
+	case r == fullBlock:
+		fill(mask, bounds, 0xff)
+	case r == upperHalfBlock:
+		fill(mask, stdimage.Rect(0, 0, g.width, g.height/2), 0xff)
+	case r == lowerHalfBlock:
+		fill(mask, stdimage.Rect(0, g.height/2, g.width, g.height), 0xff)
+	case r == lightShade:
+		fill(mask, bounds, 0x40)
+	case r == mediumShade:
+		fill(mask, bounds, 0x80)
+	case r == darkShade:
+		fill(mask, bounds, 0xc0)
+	case r > brailleBlank && r <= brailleLast:
+		g.drawBraille(mask, int(r-brailleBlank))
+	default:
+		g.drawFontGlyph(mask, r)
+	}
+	return mask
+}
+
+// drawBraille draws a square dot for every bit set in the pattern, each dot centered in its quarter/half of the cell
+func (g *glyphCache) drawBraille(mask *stdimage.Alpha, pattern int) {
+	dotWidth, dotHeight := float64(g.width)/2, float64(g.height)/4
+	size := math.Max(1, math.Round(math.Min(dotWidth, dotHeight)*0.6))
+	for bit, dot := range brailleDots {
+		if pattern&(1<<uint(bit)) == 0 {
+			continue
+		}
+		x := int(math.Round((float64(dot.X)+0.5)*dotWidth - size/2))
+		y := int(math.Round((float64(dot.Y)+0.5)*dotHeight - size/2))
+		fill(mask, stdimage.Rect(x, y, x+int(size), y+int(size)), 0xff)
+	}
+}
+
+// drawFontGlyph draws the character from the bitmap font scaled to the cell size, characters missing from the font are drawn as '?'
+func (g *glyphCache) drawFontGlyph(mask *stdimage.Alpha, r rune) {
+	dot := fixed.P(0, font.Ascent)
+	dr, glyph, glyphPoint, _, k := font.Glyph(dot, r)
+	if !k {
+		dr, glyph, glyphPoint, _, _ = font.Glyph(dot, '?')
+	}
+	native := stdimage.NewAlpha(stdimage.Rect(0, 0, fontWidth, fontHeight))
+	draw.Draw(native, dr, glyph, glyphPoint, draw.Src)
+	// nearest neighbour keeps the bitmap crisp when scaling up, smaller cells need the smoothing
+	scaler := draw.Scaler(draw.NearestNeighbor)
+	if g.height < fontHeight {
+		scaler = draw.ApproxBiLinear
+	}
+	scaler.Scale(mask, mask.Bounds(), native, native.Bounds(), draw.Src, nil)
+}
+
+func fill(mask *stdimage.Alpha, r stdimage.Rectangle, alpha uint8) {
+	draw.Draw(mask, r.Intersect(mask.Bounds()), stdimage.NewUniform(color.Alpha{A: alpha}), stdimage.Point{}, draw.Src)
+}
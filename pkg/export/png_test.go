@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	stdimage "image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// countColor counts the pixels of exactly c within r
+func countColor(m stdimage.Image, r stdimage.Rectangle, c color.NRGBA) int {
+	count := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.NRGBAModel.Convert(m.At(x, y)) == c {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestWritePNG(t *testing.T) {
+	grid := image.ParseANSI("@ \n ")
+	var buf bytes.Buffer
+
+	err := WritePNG(&buf, grid, Options{})
+	assert.NoError(t, err)
+
+	m, err := png.Decode(&buf)
+	assert.NoError(t, err)
+	// rows are padded to the widest line
+	assert.Equal(t, stdimage.Rect(0, 0, 14, 26), m.Bounds())
+	assert.NotZero(t, countColor(m, stdimage.Rect(0, 0, 7, 13), DefaultForeground))
+	assert.Equal(t, 7*13*3, countColor(m, stdimage.Rect(7, 0, 14, 26), DefaultBackground)+countColor(m, stdimage.Rect(0, 13, 7, 26), DefaultBackground))
+}
+
+func TestRasterize_Colors(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	white := DefaultForeground
+	grid := image.Grid{{
+		{Char: '█', Color: red},
+		{Char: '▀', Color: red, Background: &blue},
+		{Char: '#'},
+	}}
+
+	m, err := Rasterize(grid, Options{FontSize: 26})
+	assert.NoError(t, err)
+
+	// cells are 14x26 at twice the font's native size
+	assert.Equal(t, stdimage.Rect(0, 0, 42, 26), m.Bounds())
+	assert.Equal(t, 14*26, countColor(m, stdimage.Rect(0, 0, 14, 26), red))
+	assert.Equal(t, 14*13, countColor(m, stdimage.Rect(14, 0, 28, 13), red))
+	assert.Equal(t, 14*13, countColor(m, stdimage.Rect(14, 13, 28, 26), blue))
+	// cells without a color use the foreground
+	assert.NotZero(t, countColor(m, stdimage.Rect(28, 0, 42, 26), white))
+
+	m, err = Rasterize(grid, Options{Monochrome: true, Foreground: &blue, Background: &white})
+	assert.NoError(t, err)
+	assert.Equal(t, 7*13, countColor(m, stdimage.Rect(0, 0, 7, 13), blue))
+	assert.Zero(t, countColor(m, m.Bounds(), red))
+	assert.Equal(t, 7*7, countColor(m, stdimage.Rect(7, 6, 14, 13), white))
+}
+
+func TestRasterize_Braille(t *testing.T) {
+	// dots 1 and 8: top left and bottom right
+	m, err := Rasterize(image.Grid{{{Char: '⢁'}}}, Options{FontSize: 40})
+	assert.NoError(t, err)
+
+	bounds := m.Bounds()
+	half := stdimage.Rect(0, 0, bounds.Dx()/2, bounds.Dy()/4)
+	assert.NotZero(t, countColor(m, half, DefaultForeground))
+	assert.NotZero(t, countColor(m, half.Add(stdimage.Pt(bounds.Dx()/2, bounds.Dy()*3/4)), DefaultForeground))
+	assert.Zero(t, countColor(m, half.Add(stdimage.Pt(bounds.Dx()/2, 0)), DefaultForeground))
+}
+
+func TestRasterize_Invalid(t *testing.T) {
+	grid := image.Grid{{{Char: 'a'}}}
+	for _, opts := range []Options{{FontSize: MinFontSize - 1}, {FontSize: MaxFontSize + 1}} {
+		_, err := Rasterize(grid, opts)
+		assert.True(t, errors.As(err, &image.InvalidInputError{}), "%+v", opts)
+	}
+
+	_, err := Rasterize(image.Grid{}, Options{})
+	assert.True(t, errors.As(err, &image.InvalidInputError{}))
+
+	wide := make([]image.Cell, image.MaxDimension)
+	tall := make(image.Grid, image.MaxDimension)
+	for n := range tall {
+		tall[n] = wide
+	}
+	_, err = Rasterize(tall, Options{FontSize: MaxFontSize})
+	assert.True(t, errors.As(err, &image.InvalidInputError{}))
+}
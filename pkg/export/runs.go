@@ -0,0 +1,64 @@
+package export
+
+import (
+	"github.com/eriksywu/ascii/pkg/image"
+	"image/color"
+	"strings"
+)
+
+// run is a span of consecutive cells in a row drawn with the same colors
+type run struct {
+	text string
+	// start is the column of the first cell of the run
+	start int
+	// length is the number of cells in the run
+	length int
+	// foreground is nil for cells drawn in the default foreground
+	foreground *color.NRGBA
+	background *color.NRGBA
+}
+
+// runs splits a row into runs of cells that share their colors, so text based formats don't repeat the same color for every character
+func runs(row []image.Cell, opts Options) []run {
+	var result []run
+	for x, cell := range row {
+		var foreground *color.NRGBA
+		if !opts.Monochrome && cell.Color.A != 0 {
+			c := cell.Color
+			foreground = &c
+		}
+		background := opts.background(cell)
+		if n := len(result) - 1; n >= 0 && sameColor(result[n].foreground, foreground) && sameColor(result[n].background, background) {
+			result[n].length++
+			continue
+		}
+		result = append(result, run{start: x, length: 1, foreground: foreground, background: background})
+	}
+	// the text is built once the runs are known, appending to it cell by cell copies the whole run every time
+	for n := range result {
+		var sb strings.Builder
+		for _, cell := range row[result[n].start : result[n].start+result[n].length] {
+			sb.WriteRune(cell.Char)
+		}
+		result[n].text = sb.String()
+	}
+	return result
+}
+
+func sameColor(a, b *color.NRGBA) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// columns is the length of the widest row of the grid
+func columns(grid image.Grid) int {
+	columns := 0
+	for _, row := range grid {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/eriksywu/ascii/pkg/image"
+	"io"
+	"math"
+	"strings"
+)
+
+// baseline is where text sits within a cell, as a fraction of the cell height from the top
+const baseline = 0.8
+
+// WriteSVG writes the grid as a standalone SVG document with one text element per run of same colored characters
+// every run is stretched to its exact width in cells so the characters line up whatever monospace font the viewer falls back to
+// returns an image.InvalidInputError if the grid is empty
+func WriteSVG(w io.Writer, grid image.Grid, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	cols := columns(grid)
+	if cols == 0 {
+		return image.NewInvalidInputError(fmt.Errorf("image is empty"))
+	}
+	cellWidth, cellHeight := cellSize(opts.FontSize)
+	width, height := cols*cellWidth, len(grid)*cellHeight
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", image.FormatColor(*opts.Background))
+	fmt.Fprintf(&sb, `<g font-family="monospace" font-size="%d" fill="%s" xml:space="preserve">`+"\n", opts.FontSize, image.FormatColor(*opts.Foreground))
+	for y, row := range grid {
+		top := y * cellHeight
+		for _, r := range runs(row, opts) {
+			if r.background != nil {
+				fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", r.start*cellWidth, top, r.length*cellWidth, cellHeight, image.FormatColor(*r.background))
+			}
+			if strings.TrimSpace(r.text) == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, `<text x="%d" y="%d" textLength="%d" lengthAdjust="spacingAndGlyphs"`, r.start*cellWidth, top+int(math.Round(baseline*float64(cellHeight))), r.length*cellWidth)
+			if r.foreground != nil {
+				fmt.Fprintf(&sb, ` fill="%s"`, image.FormatColor(*r.foreground))
+			}
+			sb.WriteString(">")
+			// EscapeText also replaces characters that aren't allowed in XML at all
+			xml.EscapeText(&sb, []byte(r.text))
+			sb.WriteString("</text>\n")
+		}
+	}
+	sb.WriteString("</g>\n</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/eriksywu/ascii/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	grid := image.Grid{
+		{{Char: '<', Color: red}, {Char: '&', Color: red}, {Char: '▀', Color: red, Background: &blue}},
+		{{Char: '"'}, {Char: ' '}, {Char: '\x01'}},
+	}
+	var buf bytes.Buffer
+
+	err := WriteSVG(&buf, grid, Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="21" height="26" viewBox="0 0 21 26">
+<rect width="100%" height="100%" fill="#000000"/>
+<g font-family="monospace" font-size="13" fill="#ffffff" xml:space="preserve">
+<text x="0" y="10" textLength="14" lengthAdjust="spacingAndGlyphs" fill="#ff0000">&lt;&amp;</text>
+<rect x="14" y="0" width="7" height="13" fill="#0000ff"/>
+<text x="14" y="10" textLength="7" lengthAdjust="spacingAndGlyphs" fill="#ff0000">▀</text>
+<text x="0" y="23" textLength="21" lengthAdjust="spacingAndGlyphs">&#34; `+"�"+`</text>
+</g>
+</svg>
+`, buf.String())
+	// the document must be well formed
+	decoder := xml.NewDecoder(&buf)
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+	}
+}
+
+func TestWriteSVG_Monochrome(t *testing.T) {
+	blue := color.NRGBA{B: 255, A: 255}
+	var buf bytes.Buffer
+
+	err := WriteSVG(&buf, image.Grid{{{Char: '▀', Color: blue, Background: &blue}}}, Options{Monochrome: true, FontSize: 26, Foreground: &blue})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<g font-family="monospace" font-size="26" fill="#0000ff" xml:space="preserve">
+<text x="0" y="21" textLength="14" lengthAdjust="spacingAndGlyphs">▀</text>`)
+	assert.NotContains(t, buf.String(), `<rect x=`)
+}
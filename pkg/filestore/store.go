@@ -1,14 +1,29 @@
 package filestore
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/eriksywu/ascii/pkg/image"
 	"github.com/google/uuid"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// images are saved as json so their render options are kept alongside the ascii value
+// files without the extension are images saved before options were stored and only hold the ascii value
+const imageFileExt = ".json"
+
+// the cells of an image are saved to their own file, they're many times the size of the rest of the image and only the cell formats read them
+const cellsFileExt = ".cells.json"
+
+// imageCells are the cells of an image and of each of its frames
+type imageCells struct {
+	Cells  image.Grid
+	Frames []image.Grid
+}
+
 var _ image.ImageStore = (*FileStore)(nil)
 
 // Simple store to save to local file
@@ -32,33 +47,88 @@ func NewStore(path string) (*FileStore, error) {
 	}, nil
 }
 
-func (f FileStore) PushASCIIImage(asciiImage string, id uuid.UUID) error {
-	file, err := os.Create(filepath.Join(f.rootPath, id.String()))
-	if err != nil {
-		return err
+func (f FileStore) PushASCIIImage(asciiImage image.ASCIIImage, id uuid.UUID) error {
+	cells := imageCells{Cells: asciiImage.Cells}
+	asciiImage.Cells = nil
+	// the frames are copied so the caller's frames keep their cells
+	frames := make([]image.Frame, len(asciiImage.Frames))
+	for n, frame := range asciiImage.Frames {
+		cells.Frames = append(cells.Frames, frame.Cells)
+		frame.Cells = nil
+		frames[n] = frame
+	}
+	if asciiImage.Frames != nil {
+		asciiImage.Frames = frames
 	}
-	_, err = file.WriteString(asciiImage)
+	// the cells are written first so they're there as soon as the image is
+	if cells.Cells != nil || len(cells.Frames) > 0 {
+		content, err := json.Marshal(cells)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(f.rootPath, id.String()+cellsFileExt), content, 0644); err != nil {
+			return err
+		}
+	}
+	content, err := json.Marshal(asciiImage)
 	if err != nil {
 		return err
 	}
-	return nil
+	return ioutil.WriteFile(filepath.Join(f.rootPath, id.String()+imageFileExt), content, 0644)
+}
+
+func (f FileStore) GetASCIIImage(id uuid.UUID) (bool, *image.ASCIIImage, error) {
+	targetFile := filepath.Join(f.rootPath, id.String()+imageFileExt)
+	content, err := ioutil.ReadFile(targetFile)
+	if os.IsNotExist(err) {
+		return f.getLegacyASCIIImage(id)
+	} else if err != nil {
+		return false, nil, err
+	}
+	asciiImage := &image.ASCIIImage{}
+	if err := json.Unmarshal(content, asciiImage); err != nil {
+		return false, nil, err
+	}
+	return true, asciiImage, nil
+}
+
+func (f FileStore) GetASCIIImageCells(id uuid.UUID) (bool, *image.ASCIIImage, error) {
+	exists, asciiImage, err := f.GetASCIIImage(id)
+	if !exists || err != nil {
+		return exists, asciiImage, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(f.rootPath, id.String()+cellsFileExt))
+	// images stored without cells, or with their cells kept inline, have no cells file
+	if os.IsNotExist(err) {
+		return true, asciiImage, nil
+	} else if err != nil {
+		return false, nil, err
+	}
+	cells := imageCells{}
+	if err := json.Unmarshal(content, &cells); err != nil {
+		return false, nil, err
+	}
+	asciiImage.Cells = cells.Cells
+	for n := range asciiImage.Frames {
+		if n < len(cells.Frames) {
+			asciiImage.Frames[n].Cells = cells.Frames[n]
+		}
+	}
+	return true, asciiImage, nil
 }
 
-func (f FileStore) GetASCIIImage(id uuid.UUID) (bool, string, error) {
+func (f FileStore) getLegacyASCIIImage(id uuid.UUID) (bool, *image.ASCIIImage, error) {
 	targetFile := filepath.Join(f.rootPath, id.String())
 	if _, err := os.Stat(targetFile); os.IsNotExist(err) {
-		return false, "", nil
+		return false, nil, nil
 	} else if err != nil {
-		return false, "", err
+		return false, nil, err
 	}
 	content, err := ioutil.ReadFile(targetFile)
 	if err != nil {
-		return false, "", err
+		return false, nil, err
 	}
-	if err != nil {
-		return false, "", err
-	}
-	return true, string(content), nil
+	return true, &image.ASCIIImage{Value: string(content)}, nil
 }
 
 func (f FileStore) ListASCIIImages() ([]uuid.UUID, error) {
@@ -69,6 +139,10 @@ func (f FileStore) ListASCIIImages() ([]uuid.UUID, error) {
 			return nil
 		}
 		_, imageFileName := filepath.Split(path)
+		if strings.HasSuffix(imageFileName, cellsFileExt) {
+			return nil
+		}
+		imageFileName = strings.TrimSuffix(imageFileName, imageFileExt)
 		if id, err := uuid.Parse(imageFileName); err == nil {
 			images = append(images, id)
 		}
@@ -0,0 +1,275 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// AdjustmentKind is the name of a preprocessing step applied to the decoded image before it's converted
+type AdjustmentKind string
+
+const (
+	// AdjustBrightness adds Amount (-1 to 1) to every channel
+	AdjustBrightness AdjustmentKind = "brightness"
+	// AdjustContrast scales every channel's distance from mid gray by Amount, 1 leaves the image unchanged
+	AdjustContrast AdjustmentKind = "contrast"
+	// AdjustGamma applies a gamma curve, Amounts above 1 brighten midtones and below 1 darken them
+	AdjustGamma AdjustmentKind = "gamma"
+	// AdjustLevels stretches the image's brightness range to the full 0-255 range (auto-levels)
+	AdjustLevels AdjustmentKind = "levels"
+	// AdjustEqualize spreads brightness evenly using histogram equalization
+	AdjustEqualize AdjustmentKind = "equalize"
+	// AdjustSharpen applies an unsharp mask of strength Amount
+	AdjustSharpen AdjustmentKind = "sharpen"
+)
+
+// maxAdjustments limits how many adjustments a single image can be run through
+const maxAdjustments = 16
+
+// Adjustment is a single preprocessing step, Amount is ignored for kinds that don't take one
+type Adjustment struct {
+	Kind   AdjustmentKind
+	Amount float64
+}
+
+func (a Adjustment) String() string {
+	if !a.Kind.hasAmount() {
+		return string(a.Kind)
+	}
+	return fmt.Sprintf("%s:%s", a.Kind, strconv.FormatFloat(a.Amount, 'g', -1, 64))
+}
+
+func (k AdjustmentKind) hasAmount() bool {
+	return k != AdjustLevels && k != AdjustEqualize
+}
+
+// ParseAdjustments parses an ordered, comma separated list of adjustments, i.e "levels,brightness:0.1,gamma:1.4"
+func ParseAdjustments(value string) ([]Adjustment, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var adjustments []Adjustment
+	for _, part := range strings.Split(value, ",") {
+		name, amount := part, ""
+		if n := strings.IndexByte(part, ':'); n >= 0 {
+			name, amount = part[:n], part[n+1:]
+		}
+		adjustment, err := NewAdjustment(AdjustmentKind(strings.TrimSpace(name)), strings.TrimSpace(amount))
+		if err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	return adjustments, nil
+}
+
+// NewAdjustment parses the amount of a single adjustment, amount must be empty for kinds that don't take one
+func NewAdjustment(kind AdjustmentKind, amount string) (Adjustment, error) {
+	adjustment := Adjustment{Kind: kind}
+	if !kind.hasAmount() {
+		if amount != "" {
+			return adjustment, NewInvalidInputError(fmt.Errorf("adjustment %s doesn't take an amount", kind))
+		}
+		return adjustment, adjustment.validate()
+	}
+	f, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return adjustment, NewInvalidInputError(fmt.Errorf("adjustment %s requires a numeric amount, got %q", kind, amount))
+	}
+	adjustment.Amount = f
+	return adjustment, adjustment.validate()
+}
+
+func (a Adjustment) validate() error {
+	if math.IsNaN(a.Amount) {
+		return NewInvalidInputError(fmt.Errorf("adjustment %s amount must be a number", a.Kind))
+	}
+	switch a.Kind {
+	case AdjustBrightness:
+		if a.Amount < -1 || a.Amount > 1 {
+			return NewInvalidInputError(fmt.Errorf("brightness must be between -1 and 1"))
+		}
+	case AdjustContrast, AdjustGamma, AdjustSharpen:
+		if a.Amount <= 0 || a.Amount > 10 {
+			return NewInvalidInputError(fmt.Errorf("%s must be greater than 0 and at most 10", a.Kind))
+		}
+	case AdjustLevels, AdjustEqualize:
+	default:
+		return NewInvalidInputError(fmt.Errorf("unknown adjustment %q, must be one of %s, %s, %s, %s, %s, %s",
+			a.Kind, AdjustBrightness, AdjustContrast, AdjustGamma, AdjustLevels, AdjustEqualize, AdjustSharpen))
+	}
+	return nil
+}
+
+func validateAdjustments(adjustments []Adjustment) error {
+	if len(adjustments) > maxAdjustments {
+		return NewInvalidInputError(fmt.Errorf("at most %d adjustments can be applied", maxAdjustments))
+	}
+	for _, adjustment := range adjustments {
+		if err := adjustment.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyAdjustments runs m through every adjustment in order
+// returns m untouched if there are no adjustments
+func applyAdjustments(ctx context.Context, m image.Image, adjustments []Adjustment) (image.Image, error) {
+	if len(adjustments) == 0 {
+		return m, nil
+	}
+	bounds := m.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), m, bounds.Min, draw.Src)
+
+	for _, adjustment := range adjustments {
+		var err error
+		switch adjustment.Kind {
+		case AdjustSharpen:
+			dst, err = sharpen(ctx, dst, adjustment.Amount)
+		default:
+			err = applyLUT(ctx, dst, adjustmentLUT(dst, adjustment))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// adjustmentLUT builds the per channel lookup table for adjustments that map every value independently
+func adjustmentLUT(m *image.NRGBA, adjustment Adjustment) *[256]uint8 {
+	var lut [256]uint8
+	switch adjustment.Kind {
+	case AdjustLevels:
+		low, high := luminanceRange(luminanceHistogram(m))
+		for v := range lut {
+			lut[v] = clampChannel(float64(v-low) * 255 / float64(high-low))
+		}
+	case AdjustEqualize:
+		histogram := luminanceHistogram(m)
+		var cdf [256]int
+		total := 0
+		for v, n := range histogram {
+			total += n
+			cdf[v] = total
+		}
+		// the first non-empty bucket maps to 0
+		low := 0
+		for _, n := range cdf {
+			if n > 0 {
+				low = n
+				break
+			}
+		}
+		for v := range lut {
+			if total == low {
+				lut[v] = uint8(v)
+				continue
+			}
+			lut[v] = clampChannel(float64(cdf[v]-low) * 255 / float64(total-low))
+		}
+	default:
+		for v := range lut {
+			f := float64(v) / 255
+			switch adjustment.Kind {
+			case AdjustBrightness:
+				f += adjustment.Amount
+			case AdjustContrast:
+				f = (f-0.5)*adjustment.Amount + 0.5
+			case AdjustGamma:
+				f = math.Pow(f, 1/adjustment.Amount)
+			}
+			lut[v] = clampChannel(f * 255)
+		}
+	}
+	return &lut
+}
+
+func applyLUT(ctx context.Context, m *image.NRGBA, lut *[256]uint8) error {
+	return forEachRow(ctx, m.Bounds().Dy(), func(y int) {
+		row := m.Pix[y*m.Stride : y*m.Stride+m.Bounds().Dx()*4]
+		for n := 0; n < len(row); n += 4 {
+			row[n], row[n+1], row[n+2] = lut[row[n]], lut[row[n+1]], lut[row[n+2]]
+		}
+	})
+}
+
+// luminanceHistogram counts the pixels of every brightness, ignoring fully transparent pixels
+func luminanceHistogram(m *image.NRGBA) [256]int {
+	var histogram [256]int
+	for n := 0; n < len(m.Pix); n += 4 {
+		if m.Pix[n+3] == 0 {
+			continue
+		}
+		histogram[(int(m.Pix[n])+int(m.Pix[n+1])+int(m.Pix[n+2]))/3]++
+	}
+	return histogram
+}
+
+// luminanceRange is the darkest and brightest brightness in the histogram, ignoring the outer 0.5% on either end so a few stray pixels don't defeat the stretch
+func luminanceRange(histogram [256]int) (int, int) {
+	total := 0
+	for _, n := range histogram {
+		total += n
+	}
+	clip := total / 200
+	low, high := 0, 255
+	for count := 0; low < 255; low++ {
+		if count += histogram[low]; count > clip {
+			break
+		}
+	}
+	for count := 0; high > 0; high-- {
+		if count += histogram[high]; count > clip {
+			break
+		}
+	}
+	if high <= low {
+		return 0, 255
+	}
+	return low, high
+}
+
+// sharpen applies an unsharp mask: every pixel is pushed away from the average of its 3x3 neighbourhood by amount
+func sharpen(ctx context.Context, m *image.NRGBA, amount float64) (*image.NRGBA, error) {
+	bounds := m.Bounds()
+	dst := image.NewNRGBA(bounds)
+	err := forEachRow(ctx, bounds.Dy(), func(y int) {
+		for x := 0; x < bounds.Dx(); x++ {
+			var sum [3]int
+			count := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= bounds.Dx() || ny >= bounds.Dy() {
+						continue
+					}
+					offset := m.PixOffset(nx, ny)
+					sum[0], sum[1], sum[2] = sum[0]+int(m.Pix[offset]), sum[1]+int(m.Pix[offset+1]), sum[2]+int(m.Pix[offset+2])
+					count++
+				}
+			}
+			offset := m.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				v := float64(m.Pix[offset+c])
+				dst.Pix[offset+c] = clampChannel(v + amount*(v-float64(sum[c])/float64(count)))
+			}
+			dst.Pix[offset+3] = m.Pix[offset+3]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func clampChannel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v))))
+}
@@ -0,0 +1,113 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseAdjustments(t *testing.T) {
+	adjustments, err := ParseAdjustments("levels, brightness:-0.2,gamma:1.5,sharpen:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []Adjustment{
+		{Kind: AdjustLevels},
+		{Kind: AdjustBrightness, Amount: -0.2},
+		{Kind: AdjustGamma, Amount: 1.5},
+		{Kind: AdjustSharpen, Amount: 1},
+	}, adjustments)
+	assert.Equal(t, "brightness:-0.2", adjustments[1].String())
+	assert.Equal(t, "levels", adjustments[0].String())
+
+	for _, value := range []string{"blur:1", "brightness", "brightness:2", "gamma:0", "contrast:abc", "equalize:1", "sharpen:NaN"} {
+		_, err := ParseAdjustments(value)
+		assert.Error(t, err, value)
+		_, isInputError := err.(InvalidInputError)
+		assert.True(t, isInputError, value)
+	}
+}
+
+// grayImage is a 1 pixel high image with one pixel per value
+func grayImage(values ...uint8) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, len(values), 1))
+	for x, v := range values {
+		m.Set(x, 0, color.NRGBA{R: v, G: v, B: v, A: 255})
+	}
+	return m
+}
+
+func grayValues(m image.Image) []uint8 {
+	var values []uint8
+	for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
+		values = append(values, color.NRGBAModel.Convert(m.At(x, m.Bounds().Min.Y)).(color.NRGBA).R)
+	}
+	return values
+}
+
+func TestApplyAdjustments(t *testing.T) {
+	cases := []struct {
+		adjustment Adjustment
+		expected   []uint8
+	}{
+		{Adjustment{Kind: AdjustBrightness, Amount: 0.2}, []uint8{51, 115, 179, 255}},
+		{Adjustment{Kind: AdjustContrast, Amount: 2}, []uint8{0, 0, 129, 255}},
+		{Adjustment{Kind: AdjustGamma, Amount: 2}, []uint8{0, 128, 181, 255}},
+		// already covers the full range
+		{Adjustment{Kind: AdjustLevels}, []uint8{0, 64, 128, 255}},
+		{Adjustment{Kind: AdjustEqualize}, []uint8{0, 85, 170, 255}},
+	}
+	for _, c := range cases {
+		m, err := applyAdjustments(context.Background(), grayImage(0, 64, 128, 255), []Adjustment{c.adjustment})
+		assert.NoError(t, err, c.adjustment.String())
+		assert.Equal(t, c.expected, grayValues(m), c.adjustment.String())
+	}
+
+	// levels stretches a low contrast image to the full range
+	m, err := applyAdjustments(context.Background(), grayImage(100, 110, 120), []Adjustment{{Kind: AdjustLevels}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{0, 128, 255}, grayValues(m))
+
+	// sharpen pushes an edge apart
+	m, err = applyAdjustments(context.Background(), grayImage(100, 100, 150, 150), []Adjustment{{Kind: AdjustSharpen, Amount: 1}})
+	assert.NoError(t, err)
+	values := grayValues(m)
+	assert.Less(t, values[1], uint8(100))
+	assert.Greater(t, values[2], uint8(150))
+}
+
+func TestApplyAdjustments_Ordered(t *testing.T) {
+	brighten := Adjustment{Kind: AdjustBrightness, Amount: 0.5}
+	contrast := Adjustment{Kind: AdjustContrast, Amount: 2}
+
+	m, err := applyAdjustments(context.Background(), grayImage(64), []Adjustment{brighten, contrast})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{255}, grayValues(m))
+
+	m, err = applyAdjustments(context.Background(), grayImage(64), []Adjustment{contrast, brighten})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{128}, grayValues(m))
+}
+
+func TestApplyAdjustments_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := applyAdjustments(ctx, grayImage(0, 128), []Adjustment{{Kind: AdjustGamma, Amount: 2}})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestService_NewASCIIImageSyncE2E_Adjustments(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	adjustments := []Adjustment{{Kind: AdjustBrightness, Amount: 1}}
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeNative, Width: 4, Height: 2, Ramp: " @", Adjustments: adjustments})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	// full brightness turns every opaque pixel white
+	assert.Equal(t, "@@@@\n@@@@\n", asciiImage.Value)
+	assert.Equal(t, adjustments, asciiImage.Options.Adjustments)
+}
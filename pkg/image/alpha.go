@@ -0,0 +1,132 @@
+package image
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// AlphaMode selects how transparent pixels are converted
+type AlphaMode string
+
+const (
+	// AlphaNone treats transparent pixels as dark pixels, this is the default
+	AlphaNone AlphaMode = ""
+	// AlphaSpace renders cells that are mostly transparent (below AlphaThreshold) as uncolored spaces
+	AlphaSpace AlphaMode = "space"
+	// AlphaComposite draws the image over ConversionOptions.Background before converting it
+	AlphaComposite AlphaMode = "composite"
+	// AlphaThreshold makes every pixel either fully transparent or fully opaque by AlphaThreshold before converting it
+	// fully transparent cells are rendered as uncolored spaces like AlphaSpace
+	AlphaThreshold AlphaMode = "threshold"
+)
+
+// DefaultAlphaThreshold is the opacity (0-1) below which a pixel or cell counts as transparent
+const DefaultAlphaThreshold = 0.5
+
+// ParseAlphaMode parses a user supplied alpha mode, "none" and "" both mean transparency is ignored
+func ParseAlphaMode(value string) (AlphaMode, error) {
+	switch mode := AlphaMode(value); mode {
+	case AlphaNone, AlphaSpace, AlphaComposite, AlphaThreshold:
+		return mode, nil
+	case "none":
+		return AlphaNone, nil
+	}
+	return AlphaNone, NewInvalidInputError(fmt.Errorf("unknown alpha mode %q, must be one of none, %s, %s, %s", value, AlphaSpace, AlphaComposite, AlphaThreshold))
+}
+
+// namedColors are the colors that can be referred to by name instead of hex
+var namedColors = map[string]color.NRGBA{
+	"black": {A: 255},
+	"white": {R: 255, G: 255, B: 255, A: 255},
+}
+
+// ParseColor parses a user supplied opaque color, either as hex (#rrggbb, rrggbb or #rgb) or one of the named colors
+func ParseColor(value string) (color.NRGBA, error) {
+	if c, k := namedColors[strings.ToLower(value)]; k {
+		return c, nil
+	}
+	digits := strings.TrimPrefix(value, "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	rgb, err := hex.DecodeString(digits)
+	if err != nil || len(rgb) != 3 {
+		return color.NRGBA{}, NewInvalidInputError(fmt.Errorf("color must be formatted as #rrggbb or be one of black, white, got %q", value))
+	}
+	return color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
+
+// FormatColor formats c as #rrggbb
+func FormatColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (o ConversionOptions) alphaThreshold() float64 {
+	if o.AlphaThreshold == nil {
+		return DefaultAlphaThreshold
+	}
+	return *o.AlphaThreshold
+}
+
+// transparent reports whether a pixel or cell of color c is rendered as transparent
+func (o ConversionOptions) transparent(c color.NRGBA) bool {
+	switch o.Alpha {
+	case AlphaSpace, AlphaThreshold:
+		return float64(c.A)/255 < o.alphaThreshold()
+	}
+	return false
+}
+
+// brightness is the brightness (0-1) converters map c onto characters with
+// unless transparency is ignored the color counts at full strength, so partially transparent edges don't come out darker than they are
+func (o ConversionOptions) brightness(c color.NRGBA) float64 {
+	if o.Alpha != AlphaNone {
+		c.A = 255
+	}
+	return intensity(c)
+}
+
+// applyAlpha prepares the image's transparency for conversion according to opts.Alpha
+// returns m untouched for modes that are handled by the converters
+func applyAlpha(ctx context.Context, m image.Image, opts ConversionOptions) (image.Image, error) {
+	bounds := m.Bounds()
+	switch opts.Alpha {
+	case AlphaComposite:
+		dst := image.NewNRGBA(bounds)
+		background := opts.Background
+		background.A = 255
+		draw.Draw(dst, bounds, image.NewUniform(background), image.Point{}, draw.Src)
+		err := forEachRow(ctx, bounds.Dy(), func(row int) {
+			line := image.Rect(bounds.Min.X, bounds.Min.Y+row, bounds.Max.X, bounds.Min.Y+row+1)
+			draw.Draw(dst, line, m, line.Min, draw.Over)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dst, nil
+	case AlphaThreshold:
+		dst := image.NewNRGBA(bounds)
+		draw.Draw(dst, bounds, m, bounds.Min, draw.Src)
+		cutoff := opts.alphaThreshold() * 255
+		err := forEachRow(ctx, bounds.Dy(), func(row int) {
+			pix := dst.Pix[row*dst.Stride : row*dst.Stride+bounds.Dx()*4]
+			for n := 3; n < len(pix); n += 4 {
+				if float64(pix[n]) < cutoff {
+					pix[n] = 0
+				} else {
+					pix[n] = 255
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dst, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,154 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// halfTransparentImage is transparent on the left and opaque white on the right
+func halfTransparentImage() *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for x := 2; x < 4; x++ {
+		m.Set(x, 0, color.White)
+	}
+	return m
+}
+
+func convertWithAlpha(t *testing.T, m image.Image, mode RenderMode, opts ConversionOptions) Grid {
+	opts.Mode = mode
+	opts = opts.withDefaults()
+	m, err := applyAlpha(context.Background(), m, opts)
+	assert.NoError(t, err)
+	converter, _ := LookupConverter(mode)
+	grid, err := converter.Convert(context.Background(), m, m.Bounds().Dx(), m.Bounds().Dy(), opts)
+	assert.NoError(t, err)
+	return grid
+}
+
+func TestAlphaModes(t *testing.T) {
+	m := halfTransparentImage()
+	cases := []struct {
+		opts     ConversionOptions
+		expected string
+	}{
+		// transparent pixels count as dark, which shows when inverted
+		{ConversionOptions{Invert: true}, "@@  \n"},
+		{ConversionOptions{Alpha: AlphaSpace, Invert: true}, "    \n"},
+		{ConversionOptions{Alpha: AlphaComposite, Background: color.NRGBA{R: 255, G: 255, B: 255}}, "@@@@\n"},
+		{ConversionOptions{Alpha: AlphaComposite}, "  @@\n"},
+		{ConversionOptions{Alpha: AlphaThreshold, Invert: true}, "    \n"},
+	}
+	for _, c := range cases {
+		c.opts.Ramp = " @"
+		grid := convertWithAlpha(t, m, ModeNative, c.opts)
+		assert.Equal(t, c.expected, grid.String(), "%+v", c.opts)
+	}
+
+	// a mostly transparent white pixel is rendered at full brightness unless it's thresholded away
+	faint := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	faint.Set(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 100})
+	assert.Equal(t, " \n", convertWithAlpha(t, faint, ModeNative, ConversionOptions{Ramp: " @"}).String())
+	low, zero := 0.3, 0.0
+	assert.Equal(t, "@\n", convertWithAlpha(t, faint, ModeNative, ConversionOptions{Ramp: " @", Alpha: AlphaThreshold, AlphaThreshold: &low}).String())
+	assert.Equal(t, " \n", convertWithAlpha(t, faint, ModeNative, ConversionOptions{Ramp: " @", Alpha: AlphaThreshold}).String())
+	// 0 treats every pixel as opaque instead of falling back to the default
+	assert.Equal(t, "@\n", convertWithAlpha(t, faint, ModeNative, ConversionOptions{Ramp: " @", Alpha: AlphaThreshold, AlphaThreshold: &zero}).String())
+}
+
+func TestAlphaModes_ANSI(t *testing.T) {
+	grid := convertWithAlpha(t, halfTransparentImage(), ModeNative, ConversionOptions{Ramp: " @", Alpha: AlphaSpace})
+
+	// transparent cells get no escapes at all
+	assert.Equal(t, "  \x1b[38;2;255;255;255m@@\x1b[0m\n", grid.ANSI(ColorTrue))
+}
+
+func TestAlphaModes_Pixels(t *testing.T) {
+	// transparent top, red bottom
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	m.Set(0, 1, color.NRGBA{R: 255, A: 255})
+	m.Set(1, 0, color.NRGBA{B: 255, A: 255})
+	m.Set(1, 1, color.NRGBA{B: 255, A: 255})
+
+	opts := ConversionOptions{Mode: ModePixels, Alpha: AlphaSpace}.withDefaults()
+	converter, _ := LookupConverter(ModePixels)
+	grid, err := converter.Convert(context.Background(), m, 2, 1, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, lowerHalfBlock, grid[0][0].ColorChar)
+	assert.Nil(t, grid[0][0].Background)
+	assert.NotNil(t, grid[0][1].Background)
+	ansi := grid.ANSI(ColorTrue)
+	assert.Equal(t, "\x1b[38;2;255;0;0m▄\x1b[38;2;0;0;255m\x1b[48;2;0;0;255m▀\x1b[0m\n", ansi)
+
+	grid, err = converter.Convert(context.Background(), image.NewNRGBA(image.Rect(0, 0, 1, 2)), 1, 1, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, " \x1b[0m\n", grid.ANSI(ColorTrue))
+}
+
+func TestAlphaModes_Braille(t *testing.T) {
+	opts := ConversionOptions{Mode: ModeBraille, Alpha: AlphaSpace, Invert: true}.withDefaults()
+	converter, _ := LookupConverter(ModeBraille)
+
+	grid, err := converter.Convert(context.Background(), image.NewNRGBA(image.Rect(0, 0, 2, 4)), 1, 1, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, " \n", grid.String())
+	assert.True(t, grid[0][0].Transparent)
+}
+
+func TestParseColor(t *testing.T) {
+	for value, expected := range map[string]color.NRGBA{
+		"#ff8000": {R: 255, G: 128, A: 255},
+		"FF8000":  {R: 255, G: 128, A: 255},
+		"#f80":    {R: 255, G: 136, A: 255},
+		"White":   {R: 255, G: 255, B: 255, A: 255},
+	} {
+		c, err := ParseColor(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, c, value)
+	}
+	assert.Equal(t, "#ff8000", FormatColor(color.NRGBA{R: 255, G: 128}))
+
+	for _, value := range []string{"", "#ff80", "red", "#gggggg"} {
+		_, err := ParseColor(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestService_NewASCIIImageSyncE2E_TransparentPNG(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	data, err := ioutil.ReadFile("../../test/data/big_k8s_logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(data)), ConversionOptions{Mode: ModePixels, Width: 40, Alpha: AlphaSpace})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	// the corners of the logo are transparent
+	firstLine := strings.SplitN(asciiImage.ANSIValue, "\n", 2)[0]
+	assert.True(t, strings.HasPrefix(firstLine, "     "), firstLine)
+	assert.Equal(t, AlphaSpace, asciiImage.Options.Alpha)
+}
+
+func TestAlphaModes_DitherSkipsTransparentCells(t *testing.T) {
+	// the mostly transparent pixel is mid gray, its quantization error would push the visible pixel next to it over the ramp's midpoint
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, color.NRGBA{R: 100, G: 100, B: 100, A: 100})
+	m.Set(1, 0, color.NRGBA{R: 122, G: 122, B: 122, A: 255})
+
+	for _, dither := range []DitherMode{DitherFloydSteinberg, DitherAtkinson} {
+		grid := convertWithAlpha(t, m, ModeNative, ConversionOptions{Ramp: " @", Alpha: AlphaSpace, Dither: dither})
+		assert.Equal(t, "  \n", grid.String(), dither)
+		assert.True(t, grid[0][0].Transparent, dither)
+	}
+}
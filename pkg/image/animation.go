@@ -0,0 +1,320 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"time"
+)
+
+// frames without a delay are shown for this long, which matches what browsers do
+const defaultFrameDelay = 100 * time.Millisecond
+
+// limits on the size of decoded animations, every frame is kept as a full canvas until the animation is converted
+const (
+	// MaxAnimationCanvasPixels is the largest canvas (width*height in pixels) an animation can have
+	MaxAnimationCanvasPixels = 4 * MaxDimension * MaxDimension
+	// MaxAnimationPixels is the largest number of pixels the frames of an animation can have together (frames*canvas pixels)
+	MaxAnimationPixels = 8 * MaxDimension * MaxDimension
+)
+
+// animation is a decoded multi-frame image
+// every frame is the full canvas with all previous frames composited underneath it
+type animation struct {
+	frames []image.Image
+	delays []time.Duration
+	// loopCount is the number of times the animation plays, 0 means forever
+	loopCount int
+}
+
+// decodeAnimation decodes every frame of an animated gif or png (APNG)
+// returns nil if the image isn't animated (has less than two frames) so callers can fall back to a regular decode
+func decodeAnimation(data []byte, format string) (*animation, error) {
+	switch format {
+	case "gif":
+		return decodeGIFAnimation(data)
+	case "png":
+		return decodeAPNGAnimation(data)
+	}
+	return nil, nil
+}
+
+func decodeGIFAnimation(data []byte) (*animation, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanvasSize(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) < 2 {
+		return nil, nil
+	}
+	if err := checkAnimationSize(config.Width, config.Height, len(g.Image)); err != nil {
+		return nil, err
+	}
+
+	// gif loop count is the number of times to repeat after the first play, -1 means play once
+	loopCount := g.LoopCount + 1
+	if g.LoopCount == 0 {
+		loopCount = 0
+	}
+	anim := &animation{loopCount: loopCount}
+	canvas := image.NewNRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for n, frame := range g.Image {
+		disposal := byte(0)
+		if n < len(g.Disposal) {
+			disposal = g.Disposal[n]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.frames = append(anim.frames, cloneNRGBA(canvas))
+		anim.delays = append(anim.delays, frameDelay(time.Duration(g.Delay[n])*10*time.Millisecond))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+// apng dispose and blend operations, see https://wiki.mozilla.org/APNG_Specification
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendSource       = 0
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type pngChunk struct {
+	chunkType string
+	data      []byte
+}
+
+// apngFrame is a single fcTL chunk along with the image data that belongs to it
+type apngFrame struct {
+	width, height    int
+	xOffset, yOffset int
+	delay            time.Duration
+	disposeOp        byte
+	blendOp          byte
+	data             [][]byte
+}
+
+// decodeAPNGAnimation decodes an animated png
+// the standard library only decodes the default image, so each frame is re-assembled into a standalone png and decoded on its own
+func decodeAPNGAnimation(data []byte) (*animation, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	// chunks other than IHDR that come before the image data (i.e PLTE, tRNS), needed to decode every frame
+	var ancillary []pngChunk
+	var frames []*apngFrame
+	var current *apngFrame
+	loopCount := -1
+	seenImageData := false
+	for _, chunk := range chunks {
+		switch chunk.chunkType {
+		case "IHDR":
+			header = chunk.data
+		case "acTL":
+			if len(chunk.data) != 8 {
+				return nil, fmt.Errorf("invalid acTL chunk")
+			}
+			loopCount = int(binary.BigEndian.Uint32(chunk.data[4:]))
+		case "fcTL":
+			if current, err = parseFrameControl(chunk.data); err != nil {
+				return nil, err
+			}
+			frames = append(frames, current)
+		case "IDAT":
+			seenImageData = true
+			// the default image is only part of the animation if a fcTL precedes it
+			if current != nil {
+				current.data = append(current.data, chunk.data)
+			}
+		case "fdAT":
+			if current == nil || len(chunk.data) < 4 {
+				return nil, fmt.Errorf("invalid fdAT chunk")
+			}
+			current.data = append(current.data, chunk.data[4:])
+		case "IEND":
+		default:
+			if !seenImageData {
+				ancillary = append(ancillary, chunk)
+			}
+		}
+	}
+	// not an apng, or an apng with a single frame
+	if loopCount < 0 || len(frames) < 2 || len(header) < 8 {
+		return nil, nil
+	}
+
+	canvasWidth, canvasHeight := int(binary.BigEndian.Uint32(header[0:])), int(binary.BigEndian.Uint32(header[4:]))
+	if err := checkCanvasSize(canvasWidth, canvasHeight); err != nil {
+		return nil, err
+	}
+	if err := checkAnimationSize(canvasWidth, canvasHeight, len(frames)); err != nil {
+		return nil, err
+	}
+	anim := &animation{loopCount: loopCount}
+	canvas := image.NewNRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+	for n, frame := range frames {
+		region := image.Rect(frame.xOffset, frame.yOffset, frame.xOffset+frame.width, frame.yOffset+frame.height)
+		if region.Empty() || !region.In(canvas.Bounds()) {
+			return nil, NewInvalidInputError(fmt.Errorf("apng frame %d (%v) is outside of the %dx%d canvas", n, region, canvasWidth, canvasHeight))
+		}
+		m, err := png.Decode(bytes.NewReader(assembleAPNGFrame(header, ancillary, frame)))
+		if err != nil {
+			return nil, fmt.Errorf("decoding apng frame %d: %w", n, err)
+		}
+
+		disposeOp := frame.disposeOp
+		if n == 0 && disposeOp == apngDisposePrevious {
+			disposeOp = apngDisposeBackground
+		}
+		var previous *image.NRGBA
+		if disposeOp == apngDisposePrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		op := draw.Over
+		if frame.blendOp == apngBlendSource {
+			op = draw.Src
+		}
+		draw.Draw(canvas, region, m, m.Bounds().Min, op)
+		anim.frames = append(anim.frames, cloneNRGBA(canvas))
+		anim.delays = append(anim.delays, frame.delay)
+
+		switch disposeOp {
+		case apngDisposeBackground:
+			draw.Draw(canvas, region, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+// checkCanvasSize returns an InvalidInputError if an animation's canvas is empty or larger than MaxAnimationCanvasPixels
+// the sides are checked on their own first so their product can't overflow
+func checkCanvasSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > MaxAnimationCanvasPixels || height > MaxAnimationCanvasPixels || width*height > MaxAnimationCanvasPixels {
+		return NewInvalidInputError(fmt.Errorf("animation canvas %dx%d must be at most %d pixels", width, height, MaxAnimationCanvasPixels))
+	}
+	return nil
+}
+
+// checkAnimationSize returns an InvalidInputError if the frames of an animation add up to more than MaxAnimationPixels
+func checkAnimationSize(width, height, frames int) error {
+	if frames > MaxAnimationPixels/(width*height) {
+		return NewInvalidInputError(fmt.Errorf("animation of %d %dx%d frames must be at most %d pixels in total", frames, width, height, MaxAnimationPixels))
+	}
+	return nil
+}
+
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a png")
+	}
+	var chunks []pngChunk
+	for offset := len(pngSignature); offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 8 + length + 4
+		if length < 0 || end > len(data) {
+			return nil, fmt.Errorf("truncated png chunk")
+		}
+		chunks = append(chunks, pngChunk{
+			chunkType: string(data[offset+4 : offset+8]),
+			data:      data[offset+8 : offset+8+length],
+		})
+		offset = end
+	}
+	return chunks, nil
+}
+
+func parseFrameControl(data []byte) (*apngFrame, error) {
+	if len(data) != 26 {
+		return nil, fmt.Errorf("invalid fcTL chunk")
+	}
+	delayNum, delayDen := binary.BigEndian.Uint16(data[20:]), binary.BigEndian.Uint16(data[22:])
+	// a denominator of 0 means hundredths of a second
+	if delayDen == 0 {
+		delayDen = 100
+	}
+	return &apngFrame{
+		width:     int(binary.BigEndian.Uint32(data[4:])),
+		height:    int(binary.BigEndian.Uint32(data[8:])),
+		xOffset:   int(binary.BigEndian.Uint32(data[12:])),
+		yOffset:   int(binary.BigEndian.Uint32(data[16:])),
+		delay:     frameDelay(time.Duration(delayNum) * time.Second / time.Duration(delayDen)),
+		disposeOp: data[24],
+		blendOp:   data[25],
+	}, nil
+}
+
+// assembleAPNGFrame builds a standalone png out of a single apng frame
+func assembleAPNGFrame(header []byte, ancillary []pngChunk, frame *apngFrame) []byte {
+	frameHeader := append([]byte{}, header...)
+	binary.BigEndian.PutUint32(frameHeader[0:], uint32(frame.width))
+	binary.BigEndian.PutUint32(frameHeader[4:], uint32(frame.height))
+
+	buf := bytes.NewBuffer(append([]byte{}, pngSignature...))
+	writePNGChunk(buf, "IHDR", frameHeader)
+	for _, chunk := range ancillary {
+		writePNGChunk(buf, chunk.chunkType, chunk.data)
+	}
+	for _, data := range frame.data {
+		writePNGChunk(buf, "IDAT", data)
+	}
+	writePNGChunk(buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func writePNGChunk(buf *bytes.Buffer, chunkType string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	buf.WriteString(chunkType)
+	buf.Write(data)
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc.Sum32())
+	buf.Write(checksum[:])
+}
+
+func frameDelay(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return defaultFrameDelay
+	}
+	return delay
+}
+
+func cloneNRGBA(m *image.NRGBA) *image.NRGBA {
+	clone := image.NewNRGBA(m.Bounds())
+	copy(clone.Pix, m.Pix)
+	return clone
+}
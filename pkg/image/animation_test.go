@@ -0,0 +1,199 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// solidFrame returns a size x size image filled with c
+func solidFrame(size int, c color.Color) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			m.Set(x, y, c)
+		}
+	}
+	return m
+}
+
+func encodeTestGIF(t *testing.T, colors []color.Color, loopCount int) []byte {
+	g := &gif.GIF{LoopCount: loopCount}
+	for n, c := range colors {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		for x := 0; x < 4; x++ {
+			for y := 0; y < 4; y++ {
+				frame.Set(x, y, c)
+			}
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, (n+1)*10)
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeTestAPNG builds an apng out of full-canvas frames, each shown for 50ms
+func encodeTestAPNG(t *testing.T, frames []image.Image, numPlays uint32) []byte {
+	var header []byte
+	var frameData [][]byte
+	for _, frame := range frames {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, frame); err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := readPNGChunks(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []byte
+		for _, chunk := range chunks {
+			switch chunk.chunkType {
+			case "IHDR":
+				header = chunk.data
+			case "IDAT":
+				data = append(data, chunk.data...)
+			}
+		}
+		frameData = append(frameData, data)
+	}
+
+	bounds := frames[0].Bounds()
+	buf := bytes.NewBuffer(append([]byte{}, pngSignature...))
+	writePNGChunk(buf, "IHDR", header)
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:], numPlays)
+	writePNGChunk(buf, "acTL", actl)
+	sequence := uint32(0)
+	for n, data := range frameData {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:], 5)
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		writePNGChunk(buf, "fcTL", fctl)
+		sequence++
+		if n == 0 {
+			writePNGChunk(buf, "IDAT", data)
+			continue
+		}
+		fdat := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, sequence)
+		writePNGChunk(buf, "fdAT", append(fdat, data...))
+		sequence++
+	}
+	writePNGChunk(buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func TestDecodeAnimation_GIF(t *testing.T) {
+	data := encodeTestGIF(t, []color.Color{color.Black, color.White, color.Black}, 2)
+
+	anim, err := decodeAnimation(data, "gif")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, anim)
+	assert.Equal(t, 3, len(anim.frames))
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, anim.delays)
+	// repeats twice after the first play
+	assert.Equal(t, 3, anim.loopCount)
+	r, g, b, _ := anim.frames[1].At(0, 0).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b})
+}
+
+func TestDecodeAnimation_StillImages(t *testing.T) {
+	anim, err := decodeAnimation(encodeTestGIF(t, []color.Color{color.Black}, 0), "gif")
+	assert.NoError(t, err)
+	assert.Nil(t, anim)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, solidFrame(4, color.Black)))
+	anim, err = decodeAnimation(buf.Bytes(), "png")
+	assert.NoError(t, err)
+	assert.Nil(t, anim)
+}
+
+func TestDecodeAnimation_APNG(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	data := encodeTestAPNG(t, []image.Image{solidFrame(4, red), solidFrame(4, blue)}, 0)
+
+	anim, err := decodeAnimation(data, "png")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, anim)
+	assert.Equal(t, 2, len(anim.frames))
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 50 * time.Millisecond}, anim.delays)
+	assert.Equal(t, 0, anim.loopCount)
+	assert.Equal(t, red, color.NRGBAModel.Convert(anim.frames[0].At(1, 1)))
+	assert.Equal(t, blue, color.NRGBAModel.Convert(anim.frames[1].At(1, 1)))
+}
+
+func TestDecodeAnimation_TooLarge(t *testing.T) {
+	// a tiny gif can declare a large screen and show many single pixel frames on it
+	g := &gif.GIF{Config: image.Config{Width: 1000, Height: 1000}}
+	for n := 0; n < 100; n++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	_, err := decodeAnimation(buf.Bytes(), "gif")
+	_, k := err.(InvalidInputError)
+	assert.True(t, k, "%v", err)
+
+	// the apng canvas is checked before it's allocated
+	data := encodeTestAPNG(t, []image.Image{solidFrame(2, color.White), solidFrame(2, color.Black)}, 0)
+	binary.BigEndian.PutUint32(data[len(pngSignature)+8:], 1<<30)
+	_, err = decodeAnimation(data, "png")
+	_, k = err.(InvalidInputError)
+	assert.True(t, k, "%v", err)
+
+	// frames have to be drawn within the canvas
+	data = encodeTestAPNG(t, []image.Image{solidFrame(2, color.White), solidFrame(2, color.Black)}, 0)
+	binary.BigEndian.PutUint32(data[bytes.Index(data, []byte("fcTL"))+4+12:], 1)
+	_, err = decodeAnimation(data, "png")
+	_, k = err.(InvalidInputError)
+	assert.True(t, k, "%v", err)
+}
+
+func TestService_NewASCIIImageSyncE2E_Animation(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	data := encodeTestGIF(t, []color.Color{color.Black, color.White}, 0)
+
+	id, format, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(data)), ConversionOptions{Animate: true, Ramp: " @", Color: Color256, CellAspect: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gif", format)
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(asciiImage.Frames))
+	assert.Equal(t, "    \n    \n    \n    \n", asciiImage.Frames[0].Value)
+	assert.Equal(t, "@@@@\n@@@@\n@@@@\n@@@@\n", asciiImage.Frames[1].Value)
+	assert.NotEmpty(t, asciiImage.Frames[1].ANSIValue)
+	assert.Equal(t, asciiImage.Frames[0].Value, asciiImage.Value)
+
+	// without animate only the first frame is rendered
+	id, _, err = service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(data)), ConversionOptions{})
+	assert.NoError(t, err)
+	_, asciiImage, err = service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Empty(t, asciiImage.Frames)
+}
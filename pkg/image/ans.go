@@ -0,0 +1,414 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatANS is the source format of uploaded ANSI art (.ans) files, which are imported as is instead of being converted
+const FormatANS = "ans"
+
+// ANSI art is drawn on an 80 column screen unless its SAUCE record says otherwise
+const ansDefaultWidth = 80
+
+// the longest title and author a SAUCE record holds
+const (
+	MaxTitleLength  = 35
+	MaxAuthorLength = 20
+)
+
+// ansEOF (ctrl-z) ends the art in a .ans file, the SAUCE record follows it
+const ansEOF = 0x1a
+
+// cp437 are the characters of the upper half of code page 437, the character set of ANSI art, the lower half is ASCII
+var cp437 = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ ")
+
+// cp437Bytes maps characters back to their code page 437 byte
+var cp437Bytes = func() map[rune]byte {
+	bytes := make(map[rune]byte, len(cp437))
+	for n, r := range cp437 {
+		bytes[r] = byte(0x80 + n)
+	}
+	return bytes
+}()
+
+// EncodeCP437 returns the code page 437 byte of a character, false if code page 437 doesn't have it
+func EncodeCP437(r rune) (byte, bool) {
+	if r >= 0x20 && r < 0x7f {
+		return byte(r), true
+	}
+	b, k := cp437Bytes[r]
+	return b, k
+}
+
+func decodeCP437(b byte) rune {
+	if b < 0x80 {
+		return rune(b)
+	}
+	return cp437[b-0x80]
+}
+
+// VGAPalette are the 16 colors of ANSI art in SGR order, the last 8 are the bright (bold/iCE) variants
+var VGAPalette = [16]color.NRGBA{
+	{0, 0, 0, 255}, {170, 0, 0, 255}, {0, 170, 0, 255}, {170, 85, 0, 255},
+	{0, 0, 170, 255}, {170, 0, 170, 255}, {0, 170, 170, 255}, {170, 170, 170, 255},
+	{85, 85, 85, 255}, {255, 85, 85, 255}, {85, 255, 85, 255}, {255, 255, 85, 255},
+	{85, 85, 255, 255}, {255, 85, 255, 255}, {85, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// SAUCE data and file types of ANSI art
+const (
+	sauceDataTypeCharacter = 1
+	sauceFileTypeANSi      = 1
+)
+
+// SAUCE flags (TFlags) of character files
+const (
+	// SAUCEFlagICEColors means blink selects bright backgrounds instead of blinking
+	SAUCEFlagICEColors = 0x01
+	// SAUCEFlag8PxFont means the font is drawn 8 pixels wide, rather than the 9 pixels of VGA text mode
+	SAUCEFlag8PxFont = 0x02
+	// SAUCEFlagLegacyAspect means the art is meant to be stretched to the aspect ratio of the original VGA display
+	SAUCEFlagLegacyAspect = 0x08
+)
+
+// sauceSize is the size of a SAUCE record, comment blocks come before it in lines of sauceCommentSize
+const (
+	sauceSize        = 128
+	sauceCommentSize = 64
+	sauceID          = "SAUCE00"
+	sauceCommentID   = "COMNT"
+)
+
+// SAUCE is the metadata record trailing ANSI art files (https://www.acid.org/info/sauce/sauce.htm)
+// only the fields used by character based files are kept
+type SAUCE struct {
+	Title  string
+	Author string
+	Group  string
+	Date   time.Time
+	// FileSize is the size of the art before the EOF character
+	FileSize uint32
+	// Width and Height are the size of the art in characters
+	Width  uint16
+	Height uint16
+	Flags  uint8
+	// Font is the name of the font the art is drawn in, i.e IBM VGA
+	Font string
+}
+
+// MarshalBinary encodes the record as an ANSi file's SAUCE record, characters outside of code page 437 are replaced by '?'
+func (s SAUCE) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(sauceID)
+	writeSAUCEString(&buf, s.Title, MaxTitleLength, ' ')
+	writeSAUCEString(&buf, s.Author, MaxAuthorLength, ' ')
+	writeSAUCEString(&buf, s.Group, 20, ' ')
+	date := "        "
+	if !s.Date.IsZero() {
+		date = s.Date.Format("20060102")
+	}
+	buf.WriteString(date)
+	binary.Write(&buf, binary.LittleEndian, s.FileSize)
+	buf.WriteByte(sauceDataTypeCharacter)
+	buf.WriteByte(sauceFileTypeANSi)
+	binary.Write(&buf, binary.LittleEndian, []uint16{s.Width, s.Height, 0, 0})
+	// no comments
+	buf.WriteByte(0)
+	buf.WriteByte(s.Flags)
+	writeSAUCEString(&buf, s.Font, 22, 0)
+	return buf.Bytes(), nil
+}
+
+func writeSAUCEString(buf *bytes.Buffer, s string, size int, padding byte) {
+	n := 0
+	for _, r := range s {
+		if n == size {
+			return
+		}
+		b, k := EncodeCP437(r)
+		if !k {
+			b = '?'
+		}
+		buf.WriteByte(b)
+		n++
+	}
+	for ; n < size; n++ {
+		buf.WriteByte(padding)
+	}
+}
+
+// parseSAUCE splits a .ans file into the art and its SAUCE record, the record is nil if the file doesn't have one
+func parseSAUCE(data []byte) ([]byte, *SAUCE) {
+	if len(data) < sauceSize || string(data[len(data)-sauceSize:len(data)-sauceSize+len(sauceID)]) != sauceID {
+		return data, nil
+	}
+	record := data[len(data)-sauceSize:]
+	readString := func(from, to int) string {
+		var sb strings.Builder
+		for _, b := range bytes.TrimRight(record[from:to], " \x00") {
+			sb.WriteRune(decodeCP437(b))
+		}
+		return sb.String()
+	}
+	sauce := &SAUCE{
+		Title:    readString(7, 42),
+		Author:   readString(42, 62),
+		Group:    readString(62, 82),
+		FileSize: binary.LittleEndian.Uint32(record[90:94]),
+		Width:    binary.LittleEndian.Uint16(record[96:98]),
+		Height:   binary.LittleEndian.Uint16(record[98:100]),
+		Flags:    record[105],
+		Font:     readString(106, 128),
+	}
+	sauce.Date, _ = time.Parse("20060102", string(record[82:90]))
+	art := data[:len(data)-sauceSize]
+	// the comment block sits between the art and the record
+	if comments := int(record[104]); comments > 0 {
+		size := len(sauceCommentID) + comments*sauceCommentSize
+		if len(art) >= size && string(art[len(art)-size:len(art)-size+len(sauceCommentID)]) == sauceCommentID {
+			art = art[:len(art)-size]
+		}
+	}
+	return art, sauce
+}
+
+// isANSIArt returns whether an upload that isn't an image looks like ANSI art: it either has a SAUCE record or starts with an escape sequence
+func isANSIArt(data []byte) bool {
+	if _, sauce := parseSAUCE(data); sauce != nil {
+		return true
+	}
+	return bytes.HasPrefix(data, []byte("\x1b["))
+}
+
+// ansTerminal is the virtual screen ANSI art is drawn onto when it's parsed
+type ansTerminal struct {
+	width      int
+	grid       Grid
+	x, y       int
+	savedX     int
+	savedY     int
+	foreground int
+	background int
+	bold       bool
+	blink      bool
+	// truecolor foreground/background set by 38;2 and 48;2, these override the palette colors
+	trueForeground *color.NRGBA
+	trueBackground *color.NRGBA
+	// wrapPending is set once a character is written to the last column, the cursor only wraps when the next character is written
+	wrapPending bool
+}
+
+// ParseANS parses an ANSI art (.ans) file drawn in code page 437 into a Grid along with its SAUCE record, if it has one
+// cursor movement, line wrapping at the art's width and 16 color, bold, iCE color and truecolor escapes are supported, other escapes are skipped
+// the background is left unset where the art uses the default black background
+func ParseANS(data []byte) (Grid, *SAUCE) {
+	art, sauce := parseSAUCE(data)
+	if n := bytes.IndexByte(art, ansEOF); n >= 0 {
+		art = art[:n]
+	}
+	t := &ansTerminal{width: ansDefaultWidth, foreground: 7}
+	if sauce != nil && sauce.Width > 0 && sauce.Width <= MaxDimension {
+		t.width = int(sauce.Width)
+	}
+	for n := 0; n < len(art); n++ {
+		switch b := art[n]; b {
+		case '\x1b':
+			if n+1 >= len(art) || art[n+1] != '[' {
+				continue
+			}
+			end := n + 2
+			for end < len(art) && (art[end] < 0x40 || art[end] > 0x7e) {
+				end++
+			}
+			if end < len(art) {
+				t.escape(string(art[n+2:end]), art[end])
+			}
+			n = end
+		case '\r':
+			t.x, t.wrapPending = 0, false
+		case '\n':
+			t.x, t.wrapPending = 0, false
+			t.y++
+		default:
+			t.write(decodeCP437(b))
+		}
+	}
+	// the last line can end up empty when the art ends with a newline
+	for len(t.grid) > 0 && len(t.grid[len(t.grid)-1]) == 0 {
+		t.grid = t.grid[:len(t.grid)-1]
+	}
+	return t.grid, sauce
+}
+
+func (t *ansTerminal) write(r rune) {
+	if t.wrapPending {
+		t.x, t.wrapPending = 0, false
+		t.y++
+	}
+	// art drawn past the largest image we store is dropped
+	if t.y >= MaxDimension {
+		return
+	}
+	for len(t.grid) <= t.y {
+		t.grid = append(t.grid, nil)
+	}
+	row := t.grid[t.y]
+	for len(row) <= t.x {
+		row = append(row, Cell{Char: ' '})
+	}
+	row[t.x] = t.cell(r)
+	t.grid[t.y] = row
+	if t.x == t.width-1 {
+		t.wrapPending = true
+	} else {
+		t.x++
+	}
+}
+
+func (t *ansTerminal) cell(r rune) Cell {
+	cell := Cell{Char: r}
+	foreground := t.foreground
+	if t.bold {
+		foreground += 8
+	}
+	cell.Color = VGAPalette[foreground]
+	if t.trueForeground != nil {
+		cell.Color = *t.trueForeground
+	}
+	background := t.background
+	if t.blink {
+		background += 8
+	}
+	if t.trueBackground != nil {
+		c := *t.trueBackground
+		cell.Background = &c
+	} else if background != 0 {
+		c := VGAPalette[background]
+		cell.Background = &c
+	}
+	return cell
+}
+
+func (t *ansTerminal) escape(params string, command byte) {
+	var args []int
+	for _, param := range strings.Split(params, ";") {
+		arg, _ := strconv.Atoi(param)
+		args = append(args, arg)
+	}
+	// count is the first argument of cursor movements, which default to 1
+	count := args[0]
+	if count < 1 {
+		count = 1
+	}
+	switch command {
+	case 'm':
+		t.sgr(args)
+	case 'A':
+		t.moveTo(t.x, t.y-count)
+	case 'B':
+		t.moveTo(t.x, t.y+count)
+	case 'C':
+		t.moveTo(t.x+count, t.y)
+	case 'D':
+		t.moveTo(t.x-count, t.y)
+	case 'H', 'f':
+		x := 1
+		if len(args) > 1 && args[1] > 0 {
+			x = args[1]
+		}
+		t.moveTo(x-1, count-1)
+	case 's':
+		t.savedX, t.savedY = t.x, t.y
+	case 'u':
+		t.x, t.y = t.savedX, t.savedY
+	default:
+		return
+	}
+	t.wrapPending = false
+}
+
+// moveTo moves the cursor, keeping it on the screen
+func (t *ansTerminal) moveTo(x, y int) {
+	switch {
+	case x < 0:
+		x = 0
+	case x >= t.width:
+		x = t.width - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	t.x, t.y = x, y
+}
+
+func (t *ansTerminal) sgr(args []int) {
+	for n := 0; n < len(args); n++ {
+		switch code := args[n]; {
+		case code == 0:
+			t.foreground, t.background, t.bold, t.blink = 7, 0, false, false
+			t.trueForeground, t.trueBackground = nil, nil
+		case code == 1:
+			t.bold = true
+		case code == 5:
+			t.blink = true
+		case code == 22:
+			t.bold = false
+		case code == 25:
+			t.blink = false
+		case code >= 30 && code <= 37:
+			t.foreground, t.trueForeground = code-30, nil
+		case code >= 40 && code <= 47:
+			t.background, t.trueBackground = code-40, nil
+		case code == 39:
+			t.foreground, t.trueForeground = 7, nil
+		case code == 49:
+			t.background, t.trueBackground = 0, nil
+		case (code == 38 || code == 48) && n+4 < len(args) && args[n+1] == 2:
+			c := color.NRGBA{R: uint8(args[n+2]), G: uint8(args[n+3]), B: uint8(args[n+4]), A: 255}
+			if code == 38 {
+				t.trueForeground = &c
+			} else {
+				t.trueBackground = &c
+			}
+			n += 4
+		case (code == 38 || code == 48) && n+2 < len(args) && args[n+1] == 5:
+			c := xterm256Color(args[n+2] & 0xff)
+			if code == 38 {
+				t.trueForeground = &c
+			} else {
+				t.trueBackground = &c
+			}
+			n += 2
+		}
+	}
+}
+
+// importANS builds the stored image for an uploaded .ans file, the art is kept as is and only the title and author from the options are used
+// the SAUCE record's title and author are used where the options don't set them
+func importANS(data []byte, opts ConversionOptions) (ASCIIImage, error) {
+	grid, sauce := ParseANS(data)
+	if len(grid) == 0 {
+		return ASCIIImage{}, NewInvalidInputError(fmt.Errorf("ansi art is empty"))
+	}
+	imported := ConversionOptions{Title: opts.Title, Author: opts.Author, Color: ColorTrue, Height: len(grid)}
+	for _, row := range grid {
+		if len(row) > imported.Width {
+			imported.Width = len(row)
+		}
+	}
+	if sauce != nil {
+		if imported.Title == "" {
+			imported.Title = sauce.Title
+		}
+		if imported.Author == "" {
+			imported.Author = sauce.Author
+		}
+	}
+	return ASCIIImage{Value: grid.String(), ANSIValue: grid.ANSI(ColorTrue), SourceFormat: FormatANS, Options: imported}, nil
+}
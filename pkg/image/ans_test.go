@@ -0,0 +1,136 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseANS(t *testing.T) {
+	// cursor forward skips cells, bold brightens the foreground and blink brightens the background, colors carry over lines
+	art := "\x1b[0;31mA\x1b[2CB\r\n\x1b[1;44m\xdb\x1b[5;32m\xb0\x1b[0m\r\n"
+
+	grid, sauce := ParseANS([]byte(art))
+
+	assert.Nil(t, sauce)
+	blue := VGAPalette[4]
+	brightBlue := VGAPalette[12]
+	assert.Equal(t, Grid{
+		{{Char: 'A', Color: VGAPalette[1]}, {Char: ' '}, {Char: ' '}, {Char: 'B', Color: VGAPalette[1]}},
+		{{Char: '█', Color: VGAPalette[9], Background: &blue}, {Char: '░', Color: VGAPalette[10], Background: &brightBlue}},
+	}, grid)
+}
+
+func TestParseANS_256Colors(t *testing.T) {
+	// the 5 of a 256 color escape isn't blink and its index isn't an SGR code of its own
+	art := "\x1b[38;5;196;48;5;21mA\x1b[38;5;244mB\r\n"
+
+	grid, _ := ParseANS([]byte(art))
+
+	blue := color.NRGBA{B: 255, A: 255}
+	assert.Equal(t, Grid{
+		{{Char: 'A', Color: color.NRGBA{R: 255, A: 255}, Background: &blue}, {Char: 'B', Color: color.NRGBA{R: 128, G: 128, B: 128, A: 255}, Background: &blue}},
+	}, grid)
+}
+
+func TestParseANS_Wraps(t *testing.T) {
+	sauce, err := SAUCE{Width: 3}.MarshalBinary()
+	assert.NoError(t, err)
+	// a full line followed by a newline doesn't leave an empty line behind
+	art := append([]byte("abcdef\r\nghi\r\nj\x1a"), sauce...)
+
+	grid, parsed := ParseANS(art)
+
+	assert.Equal(t, uint16(3), parsed.Width)
+	assert.Equal(t, "abc\ndef\nghi\nj\n", grid.String())
+}
+
+func TestSAUCE_RoundTrips(t *testing.T) {
+	sauce := SAUCE{
+		Title:    "Ünïcode title",
+		Author:   "someone",
+		Group:    "group",
+		Date:     time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC),
+		FileSize: 1234,
+		Width:    80,
+		Height:   25,
+		Flags:    SAUCEFlagICEColors,
+		Font:     "IBM VGA",
+	}
+
+	record, err := sauce.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Len(t, record, sauceSize)
+	// a comment block between the art and the record is skipped
+	comment := append([]byte(sauceCommentID), bytes.Repeat([]byte{' '}, sauceCommentSize)...)
+	record[104] = 1
+	art, parsed := parseSAUCE(append(append([]byte("art"), comment...), record...))
+
+	assert.Equal(t, "art", string(art))
+	// Ü and ï are in code page 437
+	assert.Equal(t, sauce, *parsed)
+}
+
+func TestSAUCE_ReplacesCharacters(t *testing.T) {
+	record, err := SAUCE{Title: "→ a title that is much too long to fit"}.MarshalBinary()
+	assert.NoError(t, err)
+
+	_, parsed := parseSAUCE(record)
+
+	assert.Equal(t, "? a title that is much too long to", parsed.Title)
+}
+
+func TestService_NewASCIIImageSyncE2E_ImportsANS(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	record, err := SAUCE{Title: "sauce title", Author: "sauce author", Width: 80}.MarshalBinary()
+	assert.NoError(t, err)
+	art := append([]byte("\x1b[1;33m\xdf\xdc\x1b[0m\r\n\x1a"), record...)
+
+	id, format, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(art)), ConversionOptions{Author: "uploader"})
+	assert.NoError(t, err)
+	assert.Equal(t, FormatANS, format)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "▀▄\n", asciiImage.Value)
+	assert.Equal(t, "\x1b[38;2;255;255;85m▀▄\x1b[0m\n", asciiImage.ANSIValue)
+	assert.Equal(t, ConversionOptions{Width: 2, Height: 1, Color: ColorTrue, Title: "sauce title", Author: "uploader"}, asciiImage.Options)
+}
+
+func TestService_NewASCIIImageSyncE2E_ImportsANS256Colors(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	art := []byte("\x1b[38;5;46m@@\x1b[0m\r\n")
+
+	id, format, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(art)), ConversionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, FormatANS, format)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[38;2;0;255;0m@@\x1b[0m\n", asciiImage.ANSIValue)
+}
+
+func TestDetectFormat_ANSIArt(t *testing.T) {
+	format, err := detectFormat([]byte("\x1b[0mplain art"))
+	assert.NoError(t, err)
+	assert.Equal(t, FormatANS, format)
+
+	_, err = detectFormat([]byte("just some text"))
+	assert.Equal(t, UnsupportedFormatError, err)
+}
+
+func TestEncodeCP437(t *testing.T) {
+	for r, b := range map[rune]byte{'a': 'a', '█': 0xdb, '░': 0xb0, 'Ç': 0x80, '■': 0xfe} {
+		encoded, k := EncodeCP437(r)
+		assert.True(t, k, string(r))
+		assert.Equal(t, b, encoded, string(r))
+		assert.Equal(t, r, decodeCP437(b))
+	}
+	_, k := EncodeCP437('⠁')
+	assert.False(t, k)
+}
@@ -0,0 +1,162 @@
+package image
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+const (
+	ansiReset             = "\x1b[0m"
+	ansiDefaultBackground = "\x1b[49m"
+)
+
+// channel values of the 6x6x6 color cube in the xterm 256 color palette
+var xtermCubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+func foregroundEscape(mode ColorMode, c color.NRGBA) string {
+	switch mode {
+	case Color16:
+		return fmt.Sprintf("\x1b[%dm", ansi16Code(palette16.nearest(c), 30))
+	case Color256:
+		return fmt.Sprintf("\x1b[38;5;%dm", xterm256Index(c))
+	case ColorTrue:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+	return ""
+}
+
+func backgroundEscape(mode ColorMode, c color.NRGBA) string {
+	switch mode {
+	case Color16:
+		return fmt.Sprintf("\x1b[%dm", ansi16Code(palette16.nearest(c), 40))
+	case Color256:
+		return fmt.Sprintf("\x1b[48;5;%dm", xterm256Index(c))
+	case ColorTrue:
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+	return ""
+}
+
+// ansi16Code is the SGR code of one of the 16 standard colors, base is 30 for foregrounds and 40 for backgrounds
+// the bright colors (8-15) have codes of their own starting 60 above the base
+func ansi16Code(index, base int) int {
+	if index < 8 {
+		return base + index
+	}
+	return base + 60 + index - 8
+}
+
+// xterm256Index returns the closest color in the xterm 256 color palette by CIELAB distance
+// only the color cube (16-231) and grayscale ramp (232-255) are considered since the first 16 colors vary between terminals
+func xterm256Index(c color.NRGBA) int {
+	return palette256.nearest(c)
+}
+
+// ansi16Colors are the standard 16 terminal colors (xterm's defaults)
+var ansi16Colors = [16]color.NRGBA{
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// xterm256Color is the color of an index in the xterm 256 color palette
+func xterm256Color(n int) color.NRGBA {
+	switch {
+	case n < 16:
+		return ansi16Colors[n]
+	case n < 232:
+		n -= 16
+		return color.NRGBA{R: uint8(xtermCubeLevels[n/36]), G: uint8(xtermCubeLevels[n/6%6]), B: uint8(xtermCubeLevels[n%6]), A: 255}
+	}
+	gray := uint8(8 + 10*(n-232))
+	return color.NRGBA{R: gray, G: gray, B: gray, A: 255}
+}
+
+// ParseANSI parses text colored with ANSI escape sequences (like a stored ANSI rendition) back into a Grid, one row per line
+// cells without a foreground color have a Color with A = 0 and cells without a background color have a nil Background
+// escape sequences other than colors are skipped
+func ParseANSI(text string) Grid {
+	var grid Grid
+	var row []Cell
+	var foreground color.NRGBA
+	var background *color.NRGBA
+	runes := []rune(text)
+	for n := 0; n < len(runes); n++ {
+		switch r := runes[n]; {
+		case r == '\x1b' && n+1 < len(runes) && runes[n+1] == '[':
+			// CSI: parameters followed by a single final byte
+			end := n + 2
+			for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+				end++
+			}
+			if end < len(runes) && runes[end] == 'm' {
+				foreground, background = applySGR(string(runes[n+2:end]), foreground, background)
+			}
+			n = end
+		case r == '\n':
+			grid = append(grid, row)
+			row = nil
+		case r == '\r':
+		default:
+			row = append(row, Cell{Char: r, Color: foreground, Background: background})
+		}
+	}
+	if len(row) > 0 {
+		grid = append(grid, row)
+	}
+	return grid
+}
+
+// applySGR applies a "select graphic rendition" sequence (i.e 38;2;255;0;0) to the current colors
+func applySGR(params string, foreground color.NRGBA, background *color.NRGBA) (color.NRGBA, *color.NRGBA) {
+	var codes []int
+	for _, param := range strings.Split(params, ";") {
+		code, _ := strconv.Atoi(param)
+		codes = append(codes, code)
+	}
+	for n := 0; n < len(codes); n++ {
+		code := codes[n]
+		var c *color.NRGBA
+		switch {
+		case code == 0:
+			foreground, background = color.NRGBA{}, nil
+		case code == 39:
+			foreground = color.NRGBA{}
+		case code == 49:
+			background = nil
+		case code >= 30 && code <= 37, code >= 40 && code <= 47:
+			c = &ansi16Colors[code%10]
+		case code >= 90 && code <= 97, code >= 100 && code <= 107:
+			c = &ansi16Colors[code%10+8]
+		case (code == 38 || code == 48) && n+2 < len(codes) && codes[n+1] == 5:
+			extended := xterm256Color(codes[n+2] & 0xff)
+			c = &extended
+			n += 2
+		case (code == 38 || code == 48) && n+4 < len(codes) && codes[n+1] == 2:
+			extended := color.NRGBA{R: uint8(codes[n+2]), G: uint8(codes[n+3]), B: uint8(codes[n+4]), A: 255}
+			c = &extended
+			n += 4
+		}
+		if c == nil {
+			continue
+		}
+		if code == 48 || (code >= 40 && code <= 47) || code >= 100 {
+			bg := *c
+			background = &bg
+		} else {
+			foreground = *c
+		}
+	}
+	return foreground, background
+}
+
+// Grid returns the cells of the image's colored rendition, or of its monochrome rendition if it has no colored one
+func (a *ASCIIImage) Grid() Grid {
+	if a.ANSIValue != "" {
+		return ParseANSI(a.ANSIValue)
+	}
+	return ParseANSI(a.Value)
+}
@@ -0,0 +1,62 @@
+package image
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// bandsPerWorker splits the rows into a few bands per worker so uneven bands don't leave workers idle
+const bandsPerWorker = 4
+
+// forEachRow runs fn for every row in [0, rows), split into bands of consecutive rows processed by a bounded set of goroutines
+// ctx is checked before every band starts, once it's cancelled no more bands are started and ctx.Err() is returned
+func forEachRow(ctx context.Context, rows int, fn func(row int)) error {
+	if rows <= 0 {
+		return ctx.Err()
+	}
+	workers := runtime.GOMAXPROCS(0)
+	if workers > rows {
+		workers = rows
+	}
+	bandSize := rows / (workers * bandsPerWorker)
+	if bandSize < 1 {
+		bandSize = 1
+	}
+
+	bands := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for n := 0; n < workers; n++ {
+		go func() {
+			defer wg.Done()
+			for start := range bands {
+				end := start + bandSize
+				if end > rows {
+					end = rows
+				}
+				for row := start; row < end; row++ {
+					fn(row)
+				}
+			}
+		}()
+	}
+
+	var err error
+	for start := 0; start < rows; start += bandSize {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case bands <- start:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(bands)
+	wg.Wait()
+	return err
+}
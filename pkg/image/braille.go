@@ -0,0 +1,89 @@
+package image
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// braille characters are a 2x4 grid of dots, each dot is a bit offset from U+2800
+const brailleBase = 0x2800
+
+// brailleDots[y][x] is the bit for the dot at column x, row y of a braille cell
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// DefaultThreshold is the brightness (0-1) above which a pixel raises a braille dot
+const DefaultThreshold = 0.5
+
+// brailleConverter maps every 2x4 pixel block onto a braille character, one dot per pixel
+// this gives 8x the resolution of a character ramp which suits line art
+type brailleConverter struct{}
+
+func (brailleConverter) CellSize() (int, int) {
+	return 2, 4
+}
+
+func (brailleConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	scaled := resizeImage(m, width*2, height*4)
+	bounds := scaled.Bounds()
+	threshold := opts.threshold(DefaultThreshold)
+
+	// one brightness per dot, quantized to raised or not
+	pixels := make([][]color.NRGBA, height*4)
+	field := make([][]float64, height*4)
+	for y := range pixels {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pixels[y] = make([]color.NRGBA, width*2)
+		field[y] = make([]float64, width*2)
+		for x := range pixels[y] {
+			pixels[y][x] = color.NRGBAModel.Convert(scaled.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			field[y][x] = opts.brightness(pixels[y][x])
+		}
+	}
+	raised, err := quantize(ctx, field, 2, opts.Dither, thresholdLevel(threshold))
+	if err != nil {
+		return nil, err
+	}
+
+	grid := make(Grid, height)
+	for row := 0; row < height; row++ {
+		grid[row] = make([]Cell, width)
+		for col := 0; col < width; col++ {
+			var dots rune
+			var r, g, b, a, n int
+			var luminance float64
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					x, y := col*2+dx, row*4+dy
+					c := pixels[y][x]
+					// transparent pixels never raise a dot or contribute to the cell's color
+					if opts.transparent(c) {
+						continue
+					}
+					r, g, b, a, n = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A), n+1
+					luminance += field[y][x]
+					if (raised[y][x] == 1) != opts.Invert {
+						dots |= brailleDots[dy][dx]
+					}
+				}
+			}
+			if n == 0 {
+				grid[row][col] = Cell{Char: ' ', Transparent: true}
+				continue
+			}
+			grid[row][col] = Cell{
+				Char:      brailleBase + dots,
+				Color:     color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)},
+				Luminance: luminance / float64(n),
+			}
+		}
+	}
+	return grid, nil
+}
@@ -0,0 +1,103 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"github.com/qeesung/image2ascii/convert"
+	"image"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Converter converts a decoded image into a grid of width x height character cells
+// implementations should check ctx as they go and return an error wrapping ctx.Err() once it is cancelled
+type Converter interface {
+	Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error)
+	// CellSize is the number of pixels (columns, rows) each character cell represents
+	CellSize() (int, int)
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[RenderMode]Converter)
+)
+
+func init() {
+	RegisterConverter(ModeASCII, image2asciiConverter{converter: convert.NewImageConverter()})
+	RegisterConverter(ModeBraille, brailleConverter{})
+	RegisterConverter(ModePixels, pixelsConverter{})
+	RegisterConverter(ModeNative, nativeConverter{})
+	RegisterConverter(ModeEdges, edgesConverter{})
+	RegisterConverter(ModeEmoji, emojiConverter{})
+}
+
+// RegisterConverter makes a converter selectable as a render mode under the given name
+// like database/sql.Register it panics if the name is empty, already registered or converter is nil
+func RegisterConverter(name RenderMode, converter Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	if name == "" || converter == nil {
+		panic("image: RegisterConverter requires a name and a converter")
+	}
+	if _, exists := converters[name]; exists {
+		panic(fmt.Sprintf("image: RegisterConverter called twice for %s", name))
+	}
+	converters[name] = converter
+}
+
+// LookupConverter returns the converter registered under name
+func LookupConverter(name RenderMode) (Converter, bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	converter, k := converters[name]
+	return converter, k
+}
+
+// RenderModes returns the sorted names of all registered converters
+func RenderModes() []RenderMode {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	modes := make([]RenderMode, 0, len(converters))
+	for mode := range converters {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	return modes
+}
+
+func renderModeList() string {
+	modes := RenderModes()
+	names := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		names = append(names, string(mode))
+	}
+	return strings.Join(names, ", ")
+}
+
+// image2asciiConverter maps each pixel of the image (scaled down by image2ascii) onto a character ramp
+// image2ascii converts in one blocking call so cancellation is only checked before it starts
+type image2asciiConverter struct {
+	converter *convert.ImageConverter
+}
+
+func (image2asciiConverter) CellSize() (int, int) {
+	return 1, 1
+}
+
+func (c image2asciiConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	convertOpts := convert.Options{
+		Ratio:       1,
+		FixedWidth:  width,
+		FixedHeight: height,
+		FitScreen:   false,
+	}
+	grid := gridFromCharPixels(c.converter.Image2CharPixelMatrix(m, &convertOpts))
+	if err := applyRamp(ctx, grid, opts); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
@@ -0,0 +1,106 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"testing"
+)
+
+// constantConverter fills the grid with a single character
+type constantConverter struct {
+	char rune
+}
+
+func (c constantConverter) CellSize() (int, int) {
+	return 1, 1
+}
+
+func (c constantConverter) Convert(ctx context.Context, _ image.Image, width, height int, _ ConversionOptions) (Grid, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	grid := make(Grid, height)
+	for row := range grid {
+		grid[row] = make([]Cell, width)
+		for col := range grid[row] {
+			grid[row][col] = Cell{Char: c.char}
+		}
+	}
+	return grid, nil
+}
+
+func init() {
+	RegisterConverter("test-constant", constantConverter{char: 'x'})
+}
+
+func TestRegisterConverter(t *testing.T) {
+	converter, k := LookupConverter("test-constant")
+	assert.True(t, k)
+	assert.Equal(t, constantConverter{char: 'x'}, converter)
+	assert.Contains(t, RenderModes(), RenderMode("test-constant"))
+	assert.Contains(t, RenderModes(), ModeASCII)
+
+	assert.Panics(t, func() { RegisterConverter("test-constant", constantConverter{}) })
+	assert.Panics(t, func() { RegisterConverter("", constantConverter{}) })
+
+	mode, err := ParseRenderMode("test-constant")
+	assert.NoError(t, err)
+	assert.Equal(t, RenderMode("test-constant"), mode)
+	_, err = ParseRenderMode("not-registered")
+	assert.Error(t, err)
+}
+
+func TestService_NewASCIIImageSyncE2E_CustomConverter(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: "test-constant", Width: 2, Height: 2})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "xx\nxx\n", asciiImage.Value)
+}
+
+func TestService_SetDefaultMode(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	assert.Error(t, service.SetDefaultMode("not-registered"))
+	assert.NoError(t, service.SetDefaultMode("test-constant"))
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 1, Height: 1})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, RenderMode("test-constant"), asciiImage.Options.Mode)
+	assert.Equal(t, "x\n", asciiImage.Value)
+}
+
+func TestService_NewASCIIImageSyncE2E_Cancelled(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, mode := range []RenderMode{ModeASCII, ModeNative, ModeBraille, ModePixels, ModeEdges} {
+		id, _, err := service.NewASCIIImageSync(ctx, getGoodImageRCloser(), ConversionOptions{Mode: mode})
+
+		assert.Error(t, err, mode)
+		_, isProcessError := err.(InternalProcessingError)
+		assert.True(t, isProcessError, mode)
+		assert.Nil(t, id)
+	}
+}
+
+func TestConverters_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+
+	for _, mode := range []RenderMode{ModeASCII, ModeNative, ModeBraille, ModePixels, ModeEdges} {
+		converter, _ := LookupConverter(mode)
+		_, err := converter.Convert(ctx, m, 4, 4, ConversionOptions{}.withDefaults())
+		assert.True(t, errors.Is(err, context.Canceled), mode)
+	}
+}
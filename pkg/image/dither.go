@@ -0,0 +1,133 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DitherMode selects how brightness is quantized onto the few levels a character ramp or braille dot can show
+// dithering trades banding for noise, which looks much better on photographs
+type DitherMode string
+
+const (
+	// DitherNone maps every pixel onto its nearest level, this is the default
+	DitherNone DitherMode = ""
+	// DitherFloydSteinberg diffuses the quantization error of every pixel onto its neighbours
+	DitherFloydSteinberg DitherMode = "floyd-steinberg"
+	// DitherAtkinson diffuses only 3/4 of the error over a wider area, which keeps more contrast than DitherFloydSteinberg
+	DitherAtkinson DitherMode = "atkinson"
+	// DitherBayer offsets every pixel by a 4x4 ordered threshold matrix, giving a regular crosshatch pattern
+	DitherBayer DitherMode = "bayer"
+)
+
+// ParseDitherMode parses a user supplied dither mode, "none" and "" both mean no dithering
+func ParseDitherMode(value string) (DitherMode, error) {
+	switch mode := DitherMode(value); mode {
+	case DitherNone, DitherFloydSteinberg, DitherAtkinson, DitherBayer:
+		return mode, nil
+	case "none":
+		return DitherNone, nil
+	}
+	return DitherNone, NewInvalidInputError(fmt.Errorf("unknown dither mode %q, must be one of none, %s, %s, %s", value, DitherFloydSteinberg, DitherAtkinson, DitherBayer))
+}
+
+// diffusion is a single neighbour an error diffusion kernel pushes part of the error onto
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+var diffusionKernels = map[DitherMode][]diffusion{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8},
+	},
+}
+
+// bayer4 is the 4x4 Bayer index matrix
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// quantize maps every brightness (0-1) of field onto one of levels evenly spaced levels (0 is darkest) using the given dither mode
+// pick chooses the level for a brightness, letting callers move the boundaries between levels (i.e braille's threshold)
+// field is modified in place when an error diffusion mode is used
+// NaN brightnesses are skipped (their level is 0), they neither take on nor pass along any error
+func quantize(ctx context.Context, field [][]float64, levels int, mode DitherMode, pick func(v float64) int) ([][]int, error) {
+	out := make([][]int, len(field))
+	step := 1 / float64(levels-1)
+
+	kernel, diffuse := diffusionKernels[mode]
+	if !diffuse {
+		// every pixel is quantized on its own so rows can be processed in parallel
+		err := forEachRow(ctx, len(field), func(y int) {
+			out[y] = make([]int, len(field[y]))
+			for x, v := range field[y] {
+				if math.IsNaN(v) {
+					continue
+				}
+				if mode == DitherBayer {
+					v += (bayer4[y%4][x%4]+0.5)/16*step - step/2
+				}
+				out[y][x] = pick(clamp01(v))
+			}
+		})
+		return out, err
+	}
+
+	// errors flow down and right, so rows have to be processed in order
+	for y, row := range field {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out[y] = make([]int, len(row))
+		for x, v := range row {
+			if math.IsNaN(v) {
+				continue
+			}
+			level := pick(clamp01(v))
+			out[y][x] = level
+			quantErr := v - float64(level)*step
+			for _, d := range kernel {
+				nx, ny := x+d.dx, y+d.dy
+				if ny < len(field) && nx >= 0 && nx < len(field[ny]) {
+					field[ny][nx] += quantErr * d.weight
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// nearestLevel picks the level closest to v
+func nearestLevel(levels int) func(v float64) int {
+	return func(v float64) int {
+		return int(v*float64(levels-1) + 0.5)
+	}
+}
+
+// thresholdLevel picks level 1 if v is above threshold, 0 otherwise
+func thresholdLevel(threshold float64) func(v float64) int {
+	return func(v float64) int {
+		if v > threshold {
+			return 1
+		}
+		return 0
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
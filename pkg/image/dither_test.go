@@ -0,0 +1,117 @@
+package image
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// run `go test ./pkg/image -run Golden -update` to regenerate the golden files after an intended change in output
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+const goldenDir = "../../test/golden"
+
+func TestQuantize(t *testing.T) {
+	// a flat 50% gray field can't be shown with two levels without dithering
+	gray := func() [][]float64 {
+		field := make([][]float64, 4)
+		for y := range field {
+			field[y] = []float64{0.5, 0.5, 0.5, 0.5}
+		}
+		return field
+	}
+	for _, mode := range []DitherMode{DitherNone, DitherFloydSteinberg, DitherAtkinson, DitherBayer} {
+		levels, err := quantize(context.Background(), gray(), 2, mode, nearestLevel(2))
+		assert.NoError(t, err, mode)
+		lit := 0
+		for _, row := range levels {
+			for _, level := range row {
+				lit += level
+			}
+		}
+		if mode == DitherNone {
+			assert.Equal(t, 16, lit, mode)
+			continue
+		}
+		// dithered modes light roughly half of the pixels
+		assert.InDelta(t, 8, lit, 2, mode)
+	}
+}
+
+func TestQuantize_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, mode := range []DitherMode{DitherNone, DitherFloydSteinberg} {
+		_, err := quantize(ctx, [][]float64{{0.5}}, 2, mode, nearestLevel(2))
+		assert.Error(t, err, mode)
+	}
+}
+
+func TestParseDitherMode(t *testing.T) {
+	mode, err := ParseDitherMode("none")
+	assert.NoError(t, err)
+	assert.Equal(t, DitherNone, mode)
+	mode, err = ParseDitherMode("bayer")
+	assert.NoError(t, err)
+	assert.Equal(t, DitherBayer, mode)
+	_, err = ParseDitherMode("noise")
+	assert.Error(t, err)
+}
+
+func loadTestImage(t *testing.T, name string) image.Image {
+	f, err := os.Open(filepath.Join("../../test/data", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// assertGolden compares grid's text against test/golden/name, writing it instead when -update is set
+func assertGolden(t *testing.T, name string, grid Grid) {
+	path := filepath.Join(goldenDir, name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, []byte(grid.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), grid.String(), name)
+}
+
+func TestDither_Golden(t *testing.T) {
+	for _, source := range []string{"christmas_tree.png", "big_k8s_logo.png"} {
+		m := loadTestImage(t, source)
+		for _, mode := range []RenderMode{ModeNative, ModeBraille} {
+			converter, _ := LookupConverter(mode)
+			for _, dither := range []DitherMode{DitherNone, DitherFloydSteinberg, DitherAtkinson, DitherBayer} {
+				opts := ConversionOptions{Mode: mode, Width: 60, Dither: dither}.withDefaults()
+				cellWidth, _ := converter.CellSize()
+				width, height := opts.targetSize(m.Bounds(), cellWidth)
+
+				grid, err := converter.Convert(context.Background(), m, width, height, opts)
+
+				assert.NoError(t, err)
+				name := dither
+				if name == DitherNone {
+					name = "none"
+				}
+				assertGolden(t, fmt.Sprintf("%s_%s_%s.txt", source[:len(source)-len(filepath.Ext(source))], mode, name), grid)
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// EdgeDetector selects the edge detection algorithm of ModeEdges
+type EdgeDetector string
+
+const (
+	// EdgeSobel marks every cell whose gradient magnitude is above the threshold, this is the default
+	EdgeSobel EdgeDetector = "sobel"
+	// EdgeCanny thins edges down to single cell lines and drops weak edges that aren't connected to strong ones
+	EdgeCanny EdgeDetector = "canny"
+)
+
+// DefaultEdgeThreshold is the gradient magnitude (0-1, where 1 is a hard black to white edge) above which a cell is an edge
+const DefaultEdgeThreshold = 0.25
+
+// ParseEdgeDetector parses a user supplied edge detector, "" leaves the default
+func ParseEdgeDetector(value string) (EdgeDetector, error) {
+	switch detector := EdgeDetector(value); detector {
+	case "", EdgeSobel, EdgeCanny:
+		return detector, nil
+	}
+	return "", NewInvalidInputError(fmt.Errorf("unknown edge detector %q, must be one of %s, %s", value, EdgeSobel, EdgeCanny))
+}
+
+// edgesConverter draws the edges of the image with characters that follow the edge's direction, which suits diagrams and line art
+// with EdgeOverlay set, the cells in between edges are filled in from the character ramp like ModeNative
+type edgesConverter struct{}
+
+func (edgesConverter) CellSize() (int, int) {
+	return 1, 1
+}
+
+func (edgesConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	scaled := resizeImage(m, width, height)
+	bounds := scaled.Bounds()
+	threshold := opts.threshold(DefaultEdgeThreshold)
+
+	grid := make(Grid, height)
+	field := make([][]float64, height)
+	for y := range grid {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		grid[y] = make([]Cell, width)
+		field[y] = make([]float64, width)
+		for x := range grid[y] {
+			c := color.NRGBAModel.Convert(scaled.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			field[y][x] = opts.brightness(c)
+			grid[y][x] = Cell{Char: ' ', Color: c, Luminance: field[y][x]}
+		}
+	}
+	if opts.EdgeOverlay {
+		if err := applyRamp(ctx, grid, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.EdgeDetector == EdgeCanny {
+		field = gaussianBlur(field)
+	}
+	gx, gy, err := sobel(ctx, field)
+	if err != nil {
+		return nil, err
+	}
+	// cells are taller than they're wide, so a vertical step covers more distance than a horizontal one
+	aspect := opts.CellAspect
+	if aspect == 0 {
+		aspect = DefaultCellAspect
+	}
+	var edges [][]bool
+	if opts.EdgeDetector == EdgeCanny {
+		edges = canny(gx, gy, threshold)
+	} else {
+		edges = make([][]bool, height)
+		for y := range edges {
+			edges[y] = make([]bool, width)
+			for x := range edges[y] {
+				edges[y][x] = math.Hypot(gx[y][x], gy[y][x]) > threshold
+			}
+		}
+	}
+
+	for y, row := range grid {
+		for x := range row {
+			if opts.transparent(row[x].Color) {
+				row[x].Char, row[x].Transparent = ' ', true
+				continue
+			}
+			if edges[y][x] {
+				row[x].Char = edgeChar(gx[y][x], gy[y][x]*aspect)
+			}
+		}
+	}
+	return grid, nil
+}
+
+// edgeChar picks the character that runs along an edge with the given brightness gradient
+// the edge runs perpendicular to the gradient. Horizontal edges use _ along the bottom of bright areas and - along the top
+func edgeChar(gx, gy float64) rune {
+	angle := math.Atan2(gy, gx) * 180 / math.Pi
+	if angle < 0 {
+		angle += 180
+	}
+	switch {
+	case angle < 22.5 || angle >= 157.5:
+		return '|'
+	case angle < 67.5:
+		return '/'
+	case angle < 112.5:
+		// y grows downwards, a negative gradient means it's brighter above
+		if gy < 0 {
+			return '_'
+		}
+		return '-'
+	default:
+		return '\\'
+	}
+}
+
+// sobel computes the horizontal and vertical brightness gradient of every cell, scaled so a hard black to white edge is 1
+// cells on the border reuse their nearest neighbour for the cells outside of the field
+func sobel(ctx context.Context, field [][]float64) ([][]float64, [][]float64, error) {
+	height := len(field)
+	gx, gy := make([][]float64, height), make([][]float64, height)
+	err := forEachRow(ctx, height, func(y int) {
+		width := len(field[y])
+		gx[y], gy[y] = make([]float64, width), make([]float64, width)
+		at := func(x, y int) float64 {
+			return field[clampIndex(y, height)][clampIndex(x, width)]
+		}
+		for x := 0; x < width; x++ {
+			gx[y][x] = (at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)) / 4
+			gy[y][x] = (at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)) / 4
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return gx, gy, nil
+}
+
+// gaussianBlur smooths the field with a 3x3 gaussian kernel so canny doesn't pick up noise
+func gaussianBlur(field [][]float64) [][]float64 {
+	height := len(field)
+	blurred := make([][]float64, height)
+	weights := [3]float64{1, 2, 1}
+	for y := range field {
+		width := len(field[y])
+		blurred[y] = make([]float64, width)
+		for x := range field[y] {
+			var sum float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					sum += weights[dx+1] * weights[dy+1] * field[clampIndex(y+dy, height)][clampIndex(x+dx, width)]
+				}
+			}
+			blurred[y][x] = sum / 16
+		}
+	}
+	return blurred
+}
+
+// canny thins the gradient down to its local maxima across the edge, then keeps cells above threshold
+// along with any cells above threshold/2 that are connected to them
+func canny(gx, gy [][]float64, threshold float64) [][]bool {
+	height := len(gx)
+	magnitude := make([][]float64, height)
+	for y := range gx {
+		magnitude[y] = make([]float64, len(gx[y]))
+		for x := range gx[y] {
+			magnitude[y][x] = math.Hypot(gx[y][x], gy[y][x])
+		}
+	}
+	at := func(x, y int) float64 {
+		if y < 0 || y >= height || x < 0 || x >= len(magnitude[y]) {
+			return 0
+		}
+		return magnitude[y][x]
+	}
+
+	// non-maximum suppression: only keep cells that are stronger than both neighbours along the gradient
+	thin := make([][]float64, height)
+	for y := range magnitude {
+		thin[y] = make([]float64, len(magnitude[y]))
+		for x, m := range magnitude[y] {
+			angle := math.Atan2(gy[y][x], gx[y][x]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 180
+			}
+			var dx, dy int
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				dx, dy = 1, 0
+			case angle < 67.5:
+				dx, dy = 1, 1
+			case angle < 112.5:
+				dx, dy = 0, 1
+			default:
+				dx, dy = -1, 1
+			}
+			if m >= at(x+dx, y+dy) && m >= at(x-dx, y-dy) {
+				thin[y][x] = m
+			}
+		}
+	}
+
+	// hysteresis: grow strong edges into connected weak ones
+	edges := make([][]bool, height)
+	var stack []image.Point
+	for y := range thin {
+		edges[y] = make([]bool, len(thin[y]))
+		for x, m := range thin[y] {
+			if m > threshold {
+				edges[y][x] = true
+				stack = append(stack, image.Pt(x, y))
+			}
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				x, y := p.X+dx, p.Y+dy
+				if y < 0 || y >= height || x < 0 || x >= len(thin[y]) || edges[y][x] {
+					continue
+				}
+				if thin[y][x] > threshold/2 {
+					edges[y][x] = true
+					stack = append(stack, image.Pt(x, y))
+				}
+			}
+		}
+	}
+	return edges
+}
+
+func clampIndex(n, length int) int {
+	if n < 0 {
+		return 0
+	}
+	if n >= length {
+		return length - 1
+	}
+	return n
+}
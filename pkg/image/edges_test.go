@@ -0,0 +1,99 @@
+package image
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+// squareImage is a white rectangle on a black background
+func squareImage() *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, 12, 10))
+	draw.Draw(m, m.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(m, image.Rect(3, 3, 9, 7), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return m
+}
+
+func TestEdgesConverter(t *testing.T) {
+	cases := []struct {
+		opts     ConversionOptions
+		expected []string
+	}{
+		{ConversionOptions{EdgeDetector: EdgeSobel}, []string{
+			"            ",
+			"            ",
+			"  /------\\  ",
+			"  |/----\\|  ",
+			"  ||    ||  ",
+			"  ||    ||  ",
+			"  |\\____/|  ",
+			"  \\______/  ",
+			"            ",
+			"            ",
+		}},
+		// canny thins the edges down
+		{ConversionOptions{EdgeDetector: EdgeCanny}, []string{
+			"            ",
+			"            ",
+			"     --     ",
+			"   /----\\   ",
+			"   |    |   ",
+			"   |    |   ",
+			"   \\____/   ",
+			"     __     ",
+			"            ",
+			"            ",
+		}},
+		{ConversionOptions{EdgeOverlay: true, Ramp: " @"}, []string{
+			"            ",
+			"            ",
+			"  /------\\  ",
+			"  |/----\\|  ",
+			"  ||@@@@||  ",
+			"  ||@@@@||  ",
+			"  |\\____/|  ",
+			"  \\______/  ",
+			"            ",
+			"            ",
+		}},
+	}
+	converter, _ := LookupConverter(ModeEdges)
+	for _, c := range cases {
+		c.opts.Mode, c.opts.CellAspect = ModeEdges, 1
+		grid, err := converter.Convert(context.Background(), squareImage(), 12, 10, c.opts.withDefaults())
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Join(c.expected, "\n")+"\n", grid.String(), "%+v", c.opts)
+	}
+}
+
+func TestEdgeChar(t *testing.T) {
+	assert.Equal(t, '|', edgeChar(1, 0))
+	assert.Equal(t, '|', edgeChar(-1, 0.1))
+	assert.Equal(t, '-', edgeChar(0, 1))
+	assert.Equal(t, '_', edgeChar(0, -1))
+	assert.Equal(t, '/', edgeChar(1, 1))
+	assert.Equal(t, '\\', edgeChar(-1, 1))
+	// directions snap to the nearest of the four characters
+	assert.Equal(t, '-', edgeChar(0.2, 1))
+	assert.Equal(t, '/', edgeChar(1, 0.6))
+}
+
+func TestService_NewASCIIImageSyncE2E_Edges(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeEdges, Width: 16})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, EdgeSobel, asciiImage.Options.EdgeDetector)
+	assert.Empty(t, asciiImage.Options.Ramp)
+	for _, r := range asciiImage.Value {
+		assert.Contains(t, " \n|/-_\\", string(r))
+	}
+}
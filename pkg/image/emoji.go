@@ -0,0 +1,127 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// EmojiPalette selects the emoji ModeEmoji maps cells onto
+type EmojiPalette string
+
+const (
+	// EmojiSquares are the colored large squares, this is the default
+	EmojiSquares EmojiPalette = "squares"
+	// EmojiHearts are the colored hearts
+	EmojiHearts EmojiPalette = "hearts"
+	// EmojiFruit are fruit picked for their color
+	EmojiFruit EmojiPalette = "fruit"
+)
+
+// ParseEmojiPalette parses a user supplied emoji palette, "" leaves the default
+func ParseEmojiPalette(value string) (EmojiPalette, error) {
+	switch palette := EmojiPalette(value); palette {
+	case "", EmojiSquares, EmojiHearts, EmojiFruit:
+		return palette, nil
+	}
+	return "", NewInvalidInputError(fmt.Errorf("unknown emoji palette %q, must be one of %s, %s, %s", value, EmojiSquares, EmojiHearts, EmojiFruit))
+}
+
+// emoji is a single code point emoji along with the color it's mostly drawn in
+// every emoji is a single rune so it fits in a Cell, which rules out the ones that need a variation selector (i.e the red heart)
+type emoji struct {
+	char  rune
+	color color.NRGBA
+}
+
+// emojiPalettes are the emoji of every palette, colored after the most common emoji fonts
+var emojiPalettes = map[EmojiPalette][]emoji{
+	EmojiSquares: {
+		{'🟥', color.NRGBA{R: 221, G: 46, B: 68, A: 255}},
+		{'🟧', color.NRGBA{R: 244, G: 144, B: 12, A: 255}},
+		{'🟨', color.NRGBA{R: 253, G: 203, B: 88, A: 255}},
+		{'🟩', color.NRGBA{R: 120, G: 177, B: 89, A: 255}},
+		{'🟦', color.NRGBA{R: 85, G: 172, B: 238, A: 255}},
+		{'🟪', color.NRGBA{R: 170, G: 142, B: 214, A: 255}},
+		{'🟫', color.NRGBA{R: 193, G: 105, B: 79, A: 255}},
+		{'⬛', color.NRGBA{R: 49, G: 55, B: 61, A: 255}},
+		{'⬜', color.NRGBA{R: 230, G: 231, B: 232, A: 255}},
+	},
+	EmojiHearts: {
+		{'💗', color.NRGBA{R: 234, G: 89, B: 110, A: 255}},
+		{'🧡', color.NRGBA{R: 244, G: 144, B: 12, A: 255}},
+		{'💛', color.NRGBA{R: 255, G: 204, B: 77, A: 255}},
+		{'💚', color.NRGBA{R: 119, G: 178, B: 85, A: 255}},
+		{'💙', color.NRGBA{R: 93, G: 173, B: 236, A: 255}},
+		{'💜', color.NRGBA{R: 170, G: 142, B: 214, A: 255}},
+		{'🤎', color.NRGBA{R: 193, G: 105, B: 79, A: 255}},
+		{'🖤', color.NRGBA{R: 49, G: 55, B: 61, A: 255}},
+		{'🤍', color.NRGBA{R: 225, G: 232, B: 237, A: 255}},
+	},
+	EmojiFruit: {
+		{'🍎', color.NRGBA{R: 221, G: 46, B: 68, A: 255}},
+		{'🍊', color.NRGBA{R: 244, G: 144, B: 12, A: 255}},
+		{'🍋', color.NRGBA{R: 255, G: 204, B: 77, A: 255}},
+		{'🍏', color.NRGBA{R: 119, G: 178, B: 85, A: 255}},
+		{'🍐', color.NRGBA{R: 166, G: 211, B: 136, A: 255}},
+		{'🫐', color.NRGBA{R: 88, G: 100, B: 183, A: 255}},
+		{'🍇', color.NRGBA{R: 116, G: 78, B: 170, A: 255}},
+		{'🍑', color.NRGBA{R: 255, G: 136, B: 108, A: 255}},
+		{'🥥', color.NRGBA{R: 138, G: 75, B: 56, A: 255}},
+	},
+}
+
+// emojiMatchers match colors to the emoji colors of every palette, in the same order as emojiPalettes
+var emojiMatchers = func() map[EmojiPalette]*Palette {
+	matchers := make(map[EmojiPalette]*Palette, len(emojiPalettes))
+	for name, emojis := range emojiPalettes {
+		colors := make([]color.NRGBA, len(emojis))
+		for n, e := range emojis {
+			colors[n] = e.color
+		}
+		matchers[name] = NewPalette(colors...)
+	}
+	return matchers
+}()
+
+// ideographicSpace is as wide as an emoji, so transparent cells keep the rest of the line aligned
+const ideographicSpace = '　'
+
+// emojiConverter maps the average color of every cell onto the emoji of opts.EmojiPalette with the closest color
+// emoji are drawn two columns wide and about square, so every cell covers two pixel columns and opts.CellAspect defaults to 1
+type emojiConverter struct{}
+
+func (emojiConverter) CellSize() (int, int) {
+	return 2, 1
+}
+
+func (emojiConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	src, err := toRGBA(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	name := opts.EmojiPalette
+	if _, k := emojiPalettes[name]; !k {
+		name = EmojiSquares
+	}
+	palette, matcher := emojiPalettes[name], emojiMatchers[name]
+
+	grid := make(Grid, height)
+	err = forEachRow(ctx, height, func(row int) {
+		cells := make([]Cell, width)
+		for col := range cells {
+			average := averageColor(src, cellBounds(src.Bounds(), col, row, width, height))
+			if opts.transparent(average) {
+				cells[col] = Cell{Char: ideographicSpace, Transparent: true}
+				continue
+			}
+			cells[col] = Cell{Char: palette[matcher.Nearest(average)].char, Color: average, Luminance: opts.brightness(average)}
+		}
+		grid[row] = cells
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
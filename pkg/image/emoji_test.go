@@ -0,0 +1,67 @@
+package image
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEmojiConverter(t *testing.T) {
+	// red, green, blue and transparent quarters
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			switch {
+			case x < 2 && y < 2:
+				m.Set(x, y, color.NRGBA{R: 230, G: 30, B: 50, A: 255})
+			case y < 2:
+				m.Set(x, y, color.NRGBA{G: 200, A: 255})
+			case x < 2:
+				m.Set(x, y, color.NRGBA{R: 70, G: 160, B: 235, A: 255})
+			}
+		}
+	}
+	converter, _ := LookupConverter(ModeEmoji)
+
+	grid, err := converter.Convert(context.Background(), m, 2, 2, ConversionOptions{Alpha: AlphaSpace})
+	assert.NoError(t, err)
+	assert.Equal(t, "🟥🟩\n🟦　\n", grid.String())
+	assert.True(t, grid[1][1].Transparent)
+	assert.Equal(t, color.NRGBA{R: 230, G: 30, B: 50, A: 255}, grid[0][0].Color)
+
+	grid, err = converter.Convert(context.Background(), m, 2, 2, ConversionOptions{EmojiPalette: EmojiFruit, Alpha: AlphaSpace})
+	assert.NoError(t, err)
+	assert.Equal(t, "🍎🍏\n🫐　\n", grid.String())
+
+	grid, err = converter.Convert(context.Background(), m, 2, 2, ConversionOptions{EmojiPalette: EmojiHearts, Alpha: AlphaSpace})
+	assert.NoError(t, err)
+	assert.Equal(t, "💗💚\n💙　\n", grid.String())
+}
+
+func TestParseEmojiPalette(t *testing.T) {
+	palette, err := ParseEmojiPalette("hearts")
+	assert.NoError(t, err)
+	assert.Equal(t, EmojiHearts, palette)
+
+	_, err = ParseEmojiPalette("flags")
+	assert.Error(t, err)
+}
+
+func TestService_NewASCIIImageSyncE2E_Emoji(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeEmoji, Width: 8})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, EmojiSquares, asciiImage.Options.EmojiPalette)
+	assert.Equal(t, 1.0, asciiImage.Options.CellAspect)
+	// emoji are square, so a square image gets as many lines as emoji per line
+	assert.Equal(t, 8, asciiImage.Options.Height)
+	assert.Equal(t, 8*9, utf8.RuneCountInString(asciiImage.Value))
+}
@@ -0,0 +1,120 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// exif orientation values, see https://www.exif.org/Exif2-2.PDF (tag 0x0112)
+const (
+	orientationNormal = 1
+	orientationMax    = 8
+)
+
+const exifOrientationTag = 0x0112
+
+// exifOrientation returns the exif orientation stored in the image, or orientationNormal if there is none
+// phone cameras store images in sensor orientation and rely on this tag to show them upright
+func exifOrientation(data []byte, format string) int {
+	var tiffData []byte
+	switch format {
+	case "jpeg":
+		tiffData = jpegExif(data)
+	case "png":
+		chunks, _ := readPNGChunks(data)
+		for _, chunk := range chunks {
+			if chunk.chunkType == "eXIf" {
+				tiffData = chunk.data
+				break
+			}
+		}
+	case "webp":
+		tiffData = webpExif(data)
+	case "tiff":
+		tiffData = data
+	}
+	orientation := tiffOrientation(tiffData)
+	if orientation < orientationNormal || orientation > orientationMax {
+		return orientationNormal
+	}
+	return orientation
+}
+
+// jpegExif finds the tiff structured data in a jpeg's APP1 exif segment
+func jpegExif(data []byte) []byte {
+	exifHeader := []byte("Exif\x00\x00")
+	// skip the SOI marker
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xff {
+			return nil
+		}
+		marker := data[offset+1]
+		// start of scan, no more metadata segments follow
+		if marker == 0xda {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[offset+4 : end]
+		if marker == 0xe1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):]
+		}
+		offset = end
+	}
+	return nil
+}
+
+// webpExif finds the EXIF chunk of an extended webp
+func webpExif(data []byte) []byte {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+	for offset := 12; offset+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		end := offset + 8 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if string(data[offset:offset+4]) == "EXIF" {
+			return data[offset+8 : end]
+		}
+		// chunks are padded to an even length
+		offset = end + length%2
+	}
+	return nil
+}
+
+// tiffOrientation reads the orientation tag out of the first IFD of tiff structured data, returns 0 if it isn't there
+func tiffOrientation(data []byte) int {
+	if len(data) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd < 8 || ifd+2 > len(data) {
+		return 0
+	}
+	entries := int(order.Uint16(data[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(data) {
+			return 0
+		}
+		if order.Uint16(data[entry:]) == exifOrientationTag {
+			// a SHORT value is stored inline in the first two bytes of the value field
+			return int(order.Uint16(data[entry+8:]))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+
+	// register decoders for all supported upload formats
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+var UnsupportedFormatError = NewInvalidInputError(fmt.Errorf("unsupported image format, must be one of png, jpeg, gif, bmp, tiff, webp or ansi art (.ans)"))
+
+// readImage reads an uploaded image into memory and detects its format from the content
+// returns UnsupportedFormatError if the content isn't any of the registered formats
+func readImage(r io.Reader) ([]byte, string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err := detectFormat(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, format, nil
+}
+
+// detectFormat sniffs the image format by decoding the image header
+// uploads that aren't images but look like ANSI art are FormatANS
+func detectFormat(data []byte) (string, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		if isANSIArt(data) {
+			return FormatANS, nil
+		}
+		return "", UnsupportedFormatError
+	}
+	if err != nil {
+		return "", NewInvalidInputError(fmt.Errorf("invalid %s image: %v", format, err))
+	}
+	return format, nil
+}
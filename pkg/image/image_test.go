@@ -6,26 +6,40 @@ import (
 	"encoding/base64"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
-	_ "image/png" // register png decoder
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"io/ioutil"
+	"regexp"
 	"strings"
 	"testing"
 )
 
 
 type MockImageStore struct {
-	data map[uuid.UUID]string
+	data map[uuid.UUID]ASCIIImage
 }
 
-func (m *MockImageStore) PushASCIIImage(asciiImage string, id uuid.UUID) error {
+func (m *MockImageStore) PushASCIIImage(asciiImage ASCIIImage, id uuid.UUID) error {
 	m.data[id] = asciiImage
 	return nil
 }
 
-func (m *MockImageStore) GetASCIIImage(id uuid.UUID) (bool, string, error) {
+func (m *MockImageStore) GetASCIIImage(id uuid.UUID) (bool, *ASCIIImage, error) {
 	d, k:= m.data[id]
-	return k, d, nil
+	if !k {
+		return false, nil, nil
+	}
+	return k, &d, nil
+}
+
+func (m *MockImageStore) GetASCIIImageCells(id uuid.UUID) (bool, *ASCIIImage, error) {
+	return m.GetASCIIImage(id)
 }
 
 func (m *MockImageStore) ListASCIIImages() ([]uuid.UUID, error) {
@@ -36,11 +50,9 @@ var _ ImageStore = (*MockImageStore)(nil)
 
 // E2E logic and error handling tests
 func TestService_NewASCIIImageAsyncE2E_BadImage(t *testing.T) {
-	service := NewService(&MockImageStore{data: make(map[uuid.UUID]string)})
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
 
-	r := ioutil.NopCloser(strings.NewReader("this is not an image"))
-
-	id, err := service.NewASCIIImageAsync(context.Background(), r)
+	id, _, err := service.NewASCIIImageAsync(context.Background(), getTruncatedImageRCloser(), ConversionOptions{})
 
 	// explanation: the actual processing task itself will error out but this is an async call so all it does is creat the Task
 	assert.NoError(t, err)
@@ -62,11 +74,9 @@ func TestService_NewASCIIImageAsyncE2E_BadImage(t *testing.T) {
 }
 
 func TestService_NewASCIIImageSyncE2E_BadImage(t *testing.T) {
-	service := NewService(&MockImageStore{data: make(map[uuid.UUID]string)})
-
-	r := ioutil.NopCloser(strings.NewReader("this is not an image"))
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
 
-	id, err := service.NewASCIIImageSync(context.Background(), r)
+	id, _, err := service.NewASCIIImageSync(context.Background(), getTruncatedImageRCloser(), ConversionOptions{})
 
 	assert.Error(t, err)
 	assert.True(t, strings.Contains(err.Error(), "processing erro"))
@@ -76,6 +86,20 @@ func TestService_NewASCIIImageSyncE2E_BadImage(t *testing.T) {
 
 }
 
+func TestService_NewASCIIImage_UnsupportedFormat(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(strings.NewReader("this is not an image")), ConversionOptions{})
+	assert.Equal(t, UnsupportedFormatError, err)
+	assert.Nil(t, id)
+
+	// unsupported formats are rejected before any task is created, even for async requests
+	id, _, err = service.NewASCIIImageAsync(context.Background(), ioutil.NopCloser(strings.NewReader("this is not an image")), ConversionOptions{})
+	assert.Equal(t, UnsupportedFormatError, err)
+	assert.Nil(t, id)
+	assert.Equal(t, 0, len(service.asyncTasks))
+}
+
 // base64 string rep of an actual png image: http://www.schaik.com/pngsuite/basn0g01.png
 const testPNGBase64Representation = "iVBORw0KGgoAAAANSUhEUgAAACAAAAAgAQAAAABbAUdZAAAABGdBTUEAAYagMeiWXwAAAFtJREFUeJwtzLEJAzAMBdHr0gSySiALejRvkBU8gsGNCmFFB1Hx4IovqurSpIRszqklUwbnUzRXEuIRsiG/SyY9G0JzJSVei9qynm9qyjBpLp0pYW7pbzBl8L8fEIdJL9AvFMkAAAAASUVORK5CYII="
 
@@ -88,10 +112,49 @@ func getGoodImageRCloser() io.ReadCloser {
 	return ioutil.NopCloser(bytes.NewReader(data))
 }
 
+// getTruncatedImageRCloser returns a png that has a valid header but is cut off before any image data
+func getTruncatedImageRCloser() io.ReadCloser {
+	data, err := base64.StdEncoding.DecodeString(testPNGBase64Representation)
+	if err != nil {
+		// not supposed to get here
+		panic(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data[:40]))
+}
+
+func TestService_NewASCIIImageSyncE2E_Formats(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			m.Set(x, y, color.NRGBA{R: uint8(x * 16), G: uint8(y * 16), B: 128, A: 255})
+		}
+	}
+	encoders := map[string]func(io.Writer, image.Image) error{
+		"png":  png.Encode,
+		"jpeg": func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, nil) },
+		"gif":  func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) },
+		"bmp":  bmp.Encode,
+		"tiff": func(w io.Writer, m image.Image) error { return tiff.Encode(w, m, nil) },
+	}
+	for format, encode := range encoders {
+		service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+		buf := &bytes.Buffer{}
+		assert.NoError(t, encode(buf, m), format)
+
+		id, detectedFormat, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(buf), ConversionOptions{})
+
+		assert.NoError(t, err, format)
+		assert.Equal(t, format, detectedFormat)
+		_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+		assert.NoError(t, err, format)
+		assert.Equal(t, format, asciiImage.SourceFormat)
+	}
+}
+
 func TestService_NewASCIIImageAsyncE2E_GoodImage(t *testing.T) {
-	service := NewService(&MockImageStore{data: make(map[uuid.UUID]string)})
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
 
-	id, err := service.NewASCIIImageAsync(context.Background(), getGoodImageRCloser())
+	id, _, err := service.NewASCIIImageAsync(context.Background(), getGoodImageRCloser(), ConversionOptions{})
 
 	// explanation: the actual processing task itself will error out but this is an async call so all it does is creat the Task
 	assert.NoError(t, err)
@@ -107,13 +170,13 @@ func TestService_NewASCIIImageAsyncE2E_GoodImage(t *testing.T) {
 	finished, asciiImage,  err := service.GetASCIIImage(context.Background(), *id)
 	assert.NoError(t, err)
 	assert.True(t, finished)
-	t.Logf("generated ascci image: \n%s", asciiImage)
+	t.Logf("generated ascci image: \n%s", asciiImage.Value)
 }
 
 func TestService_NewASCIIImageSyncE2E_GoodImage(t *testing.T) {
-	service := NewService(&MockImageStore{data: make(map[uuid.UUID]string)})
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
 
-	id, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser())
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, id)
@@ -122,5 +185,328 @@ func TestService_NewASCIIImageSyncE2E_GoodImage(t *testing.T) {
 	finished, asciiImage,  err := service.GetASCIIImage(context.Background(), *id)
 	assert.NoError(t, err)
 	assert.True(t, finished)
-	t.Logf("generated ascci image: \n%s", asciiImage)
+	t.Logf("generated ascci image: \n%s", asciiImage.Value)
+}
+
+func TestService_NewASCIIImageSyncE2E_Options(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 16})
+	assert.NoError(t, err)
+	assert.NotNil(t, id)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	// test image is 32x32 so height is derived from the width, halved since terminal cells are twice as tall as they're wide
+	assert.Equal(t, ConversionOptions{Mode: ModeASCII, Width: 16, Height: 8, Ramp: DefaultRamp, CellAspect: DefaultCellAspect}, asciiImage.Options)
+	lines := strings.Split(strings.TrimSuffix(asciiImage.Value, "\n"), "\n")
+	assert.Equal(t, 8, len(lines))
+	assert.Equal(t, 16, len(lines[0]))
+}
+
+func TestService_NewASCIIImageSync_InvalidOptions(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: MaxDimension + 1})
+	assert.Error(t, err)
+	_, isInputError := err.(InvalidInputError)
+	assert.True(t, isInputError)
+	assert.Nil(t, id)
+	assert.Equal(t, 0, len(service.asyncTasks))
+}
+
+func TestConversionOptions_TargetSize(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 200)
+	testCases := []struct {
+		opts          ConversionOptions
+		cellWidth     int
+		width, height int
+	}{
+		// square cells
+		{ConversionOptions{CellAspect: 1}, 1, 400, 200},
+		{ConversionOptions{CellAspect: 1, Scale: 0.5}, 1, 200, 100},
+		{ConversionOptions{CellAspect: 1, Width: 100}, 1, 100, 50},
+		{ConversionOptions{CellAspect: 1, Height: 100}, 1, 200, 100},
+		{ConversionOptions{CellAspect: 1, Width: 10, Height: 10, Scale: 2}, 1, 10, 10},
+		{ConversionOptions{CellAspect: 1, Scale: 0.001}, 1, 1, 1},
+		// terminal cells are about twice as tall as they're wide by default
+		{ConversionOptions{}, 1, 400, 100},
+		{ConversionOptions{Width: 100}, 1, 100, 25},
+		{ConversionOptions{Height: 25}, 1, 100, 25},
+		{ConversionOptions{Width: 100, CellAspect: 0.4}, 1, 100, 20},
+		// braille cells are 2x4 pixels
+		{ConversionOptions{}, 2, 200, 50},
+		{ConversionOptions{Width: 100}, 2, 100, 25},
+		{ConversionOptions{Height: 25}, 2, 100, 25},
+		// half block cells are 1x2 pixels
+		{ConversionOptions{Width: 100}, 1, 100, 25},
+	}
+	for _, tc := range testCases {
+		width, height := tc.opts.targetSize(bounds, tc.cellWidth)
+		assert.Equal(t, tc.width, width, "%+v", tc)
+		assert.Equal(t, tc.height, height, "%+v", tc)
+	}
+}
+
+func TestConversionOptions_TargetSizeClamped(t *testing.T) {
+	testCases := []struct {
+		opts          ConversionOptions
+		bounds        image.Rectangle
+		width, height int
+	}{
+		{ConversionOptions{}, image.Rect(0, 0, 20000, 1000), 2000, 50},
+		{ConversionOptions{CellAspect: 1, Scale: 4}, image.Rect(0, 0, 1000, 500), 2000, 1000},
+		// a tall image's derived height is clamped, shrinking the width along with it
+		{ConversionOptions{CellAspect: 1, Width: 100}, image.Rect(0, 0, 100, 20000), 10, 2000},
+	}
+	for _, tc := range testCases {
+		width, height := tc.opts.targetSize(tc.bounds, 1)
+		assert.Equal(t, tc.width, width, "%+v", tc)
+		assert.Equal(t, tc.height, height, "%+v", tc)
+	}
+}
+
+func TestService_NewASCIIImageSyncE2E_Color(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 8, Color: ColorTrue})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Contains(t, asciiImage.ANSIValue, "\x1b[38;2;")
+	// the colored rendition should be the monochrome rendition once the escapes are stripped
+	stripped := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(asciiImage.ANSIValue, "")
+	assert.Equal(t, asciiImage.Value, stripped)
+	t.Logf("generated colored ascii image: \n%s", asciiImage.ANSIValue)
+}
+
+func TestService_NewASCIIImageSyncE2E_NoColor(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 8})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Empty(t, asciiImage.ANSIValue)
+}
+
+func TestXterm256Index(t *testing.T) {
+	testCases := []struct {
+		c     color.NRGBA
+		index int
+	}{
+		{color.NRGBA{R: 0, G: 0, B: 0, A: 255}, 16},
+		{color.NRGBA{R: 255, G: 255, B: 255, A: 255}, 231},
+		{color.NRGBA{R: 255, G: 0, B: 0, A: 255}, 196},
+		{color.NRGBA{R: 0, G: 135, B: 255, A: 255}, 33},
+		// mid grays land on the grayscale ramp rather than the cube
+		{color.NRGBA{R: 128, G: 128, B: 128, A: 255}, 244},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.index, xterm256Index(tc.c), "%v", tc.c)
+	}
+}
+
+func TestXterm256Color_RoundTrips(t *testing.T) {
+	for n := 16; n < 256; n++ {
+		assert.Equal(t, n, xterm256Index(xterm256Color(n)), "%d", n)
+	}
+}
+
+func TestParseANSI(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 128, A: 255}
+	grid := Grid{
+		{{Char: 'a', Color: red}, {Char: upperHalfBlock, Color: red, Background: &blue, ColorChar: upperHalfBlock}},
+		{{Char: ' ', Transparent: true}, {Char: 'b', Color: blue}},
+	}
+
+	parsed := ParseANSI(grid.ANSI(ColorTrue))
+
+	assert.Equal(t, Grid{
+		{{Char: 'a', Color: red}, {Char: upperHalfBlock, Color: red, Background: &blue}},
+		{{Char: ' '}, {Char: 'b', Color: blue}},
+	}, parsed)
+}
+
+func TestParseANSI_PaletteColors(t *testing.T) {
+	parsed := ParseANSI("\x1b[38;5;196;48;5;33ma\x1b[31;49mb\x1b[39mc\x1b[2Kd")
+
+	bg := color.NRGBA{R: 0, G: 135, B: 255, A: 255}
+	assert.Equal(t, Grid{{
+		{Char: 'a', Color: color.NRGBA{R: 255, A: 255}, Background: &bg},
+		{Char: 'b', Color: color.NRGBA{R: 205, A: 255}},
+		{Char: 'c'},
+		// non color escapes are skipped
+		{Char: 'd'},
+	}}, parsed)
+}
+
+func TestService_NewASCIIImageSyncE2E_RampAndInvert(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 8, Ramp: "ab"})
+	assert.NoError(t, err)
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", asciiImage.Options.Ramp)
+	assert.Empty(t, strings.Trim(asciiImage.Value, "ab\n"))
+
+	id, _, err = service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 8, Ramp: "ab", Invert: true})
+	assert.NoError(t, err)
+	_, invertedImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	swapped := strings.NewReplacer("a", "b", "b", "a").Replace(asciiImage.Value)
+	assert.Equal(t, swapped, invertedImage.Value)
+}
+
+func TestParseRamp(t *testing.T) {
+	ramp, err := ParseRamp("blocks")
+	assert.NoError(t, err)
+	assert.Equal(t, " ░▒▓█", ramp)
+
+	ramp, err = ParseRamp(" .oO")
+	assert.NoError(t, err)
+	assert.Equal(t, " .oO", ramp)
+
+	ramp, err = ParseRamp("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", ramp)
+
+	for _, invalid := range []string{"x", "a\nb", strings.Repeat("a", maxRampLength+1)} {
+		_, err = ParseRamp(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRampChar(t *testing.T) {
+	ramp := []rune(" .oO@")
+	black := color.NRGBA{A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	gray := color.NRGBA{R: 128, G: 128, B: 128, A: 255}
+
+	assert.Equal(t, ' ', rampChar(ramp, false, black))
+	assert.Equal(t, '@', rampChar(ramp, false, white))
+	assert.Equal(t, 'o', rampChar(ramp, false, gray))
+	assert.Equal(t, '@', rampChar(ramp, true, black))
+	assert.Equal(t, ' ', rampChar(ramp, true, white))
+}
+
+func TestBrailleRenderer(t *testing.T) {
+	// only the first column is white
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			if x == 0 {
+				m.Set(x, y, color.White)
+			} else {
+				m.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	grid, err := brailleConverter{}.Convert(context.Background(), m, 2, 1, ConversionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "⡇⠀\n", grid.String())
+
+	grid, err = brailleConverter{}.Convert(context.Background(), m, 2, 1, ConversionOptions{Invert: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "⢸⣿\n", grid.String())
+}
+
+func TestService_NewASCIIImageSyncE2E_Braille(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeBraille, Width: 16})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, ModeBraille, asciiImage.Options.Mode)
+	lines := strings.Split(strings.TrimSuffix(asciiImage.Value, "\n"), "\n")
+	assert.Equal(t, 8, len(lines))
+	for _, line := range lines {
+		assert.Equal(t, 16, len([]rune(line)))
+		for _, r := range line {
+			assert.True(t, r >= brailleBase && r <= brailleBase+0xff, "%q is not a braille character", r)
+		}
+	}
+	t.Logf("generated braille image: \n%s", asciiImage.Value)
+}
+
+func TestBrailleConverter_ZeroThreshold(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 4))
+	for n := 0; n < 8; n++ {
+		m.Set(n%2, n/2, color.NRGBA{R: 10, G: 10, B: 10, A: 255})
+	}
+	zero := 0.0
+
+	dark, err := brailleConverter{}.Convert(context.Background(), m, 1, 1, ConversionOptions{})
+	assert.NoError(t, err)
+	lit, err := brailleConverter{}.Convert(context.Background(), m, 1, 1, ConversionOptions{Threshold: &zero})
+	assert.NoError(t, err)
+
+	// a threshold of 0 raises a dot for every pixel that isn't black instead of falling back to the default
+	assert.Equal(t, "⠀\n", dark.String())
+	assert.Equal(t, "⣿\n", lit.String())
+}
+
+func TestPixelsRenderer(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 128, A: 255}
+	m := image.NewNRGBA(image.Rect(0, 0, 1, 2))
+	m.Set(0, 0, red)
+	m.Set(0, 1, blue)
+
+	threshold := 0.2
+	grid, err := pixelsConverter{}.Convert(context.Background(), m, 1, 1, ConversionOptions{Threshold: &threshold})
+	assert.NoError(t, err)
+
+	// the luminance is the average of both pixels
+	assert.Equal(t, Cell{Char: upperHalfBlock, Color: red, Background: &blue, ColorChar: upperHalfBlock, Luminance: (255.0/765 + 128.0/765) / 2}, grid[0][0])
+	assert.Equal(t, "\x1b[38;2;255;0;0m\x1b[48;2;0;0;128m▀\x1b[0m\n", grid.ANSI(ColorTrue))
+}
+
+func TestService_NewASCIIImageSyncE2E_KeepsCells(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeNative, Width: 16})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, asciiImage.Value, asciiImage.Cells.String())
+	// the darkest and brightest cells get the ends of the ramp
+	var darkest, brightest Cell
+	darkest.Luminance = 2
+	for _, row := range asciiImage.Cells {
+		for _, cell := range row {
+			if cell.Luminance < darkest.Luminance {
+				darkest = cell
+			}
+			if cell.Luminance > brightest.Luminance {
+				brightest = cell
+			}
+		}
+	}
+	assert.Equal(t, rune(DefaultRamp[0]), darkest.Char)
+	assert.Equal(t, rune(DefaultRamp[len(DefaultRamp)-1]), brightest.Char)
+}
+
+func TestService_NewASCIIImageSyncE2E_Pixels(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModePixels, Width: 16})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	// pixels is always colored
+	assert.Equal(t, ColorTrue, asciiImage.Options.Color)
+	assert.Equal(t, 8, asciiImage.Options.Height)
+	assert.Contains(t, asciiImage.ANSIValue, "\x1b[48;2;")
+	assert.Equal(t, 8, strings.Count(asciiImage.ANSIValue, "\n"))
+	t.Logf("generated pixels image: \n%s", asciiImage.ANSIValue)
 }
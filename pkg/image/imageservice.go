@@ -7,18 +7,17 @@ import (
 	"github.com/eriksywu/ascii/pkg/logging"
 	async "github.com/eriksywu/go-async"
 	"github.com/google/uuid"
-	"github.com/qeesung/image2ascii/convert"
 	"github.com/sirupsen/logrus"
 	"image"
-	_ "image/png" // register png decoder
 	"io"
 	"io/ioutil"
 )
 
 
 type Service struct {
-	imageConverter *convert.ImageConverter
-	imageStore     ImageStore
+	imageStore ImageStore
+	// defaultMode is the converter used for requests that don't ask for a specific render mode
+	defaultMode RenderMode
 
 	//asyncTasks stores all currently running image processing tasks
 	//Shameless plug: using my own go-async pkg here
@@ -27,44 +26,69 @@ type Service struct {
 }
 
 func NewService(imageStore ImageStore) *Service {
-	service := &Service{imageStore: imageStore}
-	service.imageConverter = convert.NewImageConverter()
+	service := &Service{imageStore: imageStore, defaultMode: ModeASCII}
 	service.asyncTasks = make(map[uuid.UUID]*async.Task)
 	return service
 }
 
-func (i *Service) NewASCIIImageAsync(ctx context.Context, r io.ReadCloser) (*uuid.UUID, error) {
+// SetDefaultMode sets the render mode used for requests that don't ask for one, mode must be the name of a registered Converter
+func (i *Service) SetDefaultMode(mode RenderMode) error {
+	if _, k := LookupConverter(mode); !k {
+		return NewInvalidInputError(fmt.Errorf("unknown render mode %q, must be one of %s", mode, renderModeList()))
+	}
+	i.defaultMode = mode
+	return nil
+}
+
+// NewASCIIImageAsync starts converting the image and returns the new image's id and detected format without waiting for the conversion to finish
+func (i *Service) NewASCIIImageAsync(ctx context.Context, r io.ReadCloser, opts ConversionOptions) (*uuid.UUID, string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, "", err
+	}
+	// input ReadCloser will be auto-closed at the end of the httphandlefunc. We need to copy it.
+	// this also lets us reject unsupported formats before kicking off the task
+	rCopyBytes, format, err := readImage(r)
+	if err != nil {
+		return nil, "", err
+	}
 	id := uuid.New()
 	// construct a new context that's not tied to the request context to decouple this async op from the request's cancelFunc
 	// but copy over context-based logger
 	asyncContext := context.WithValue(context.Background(), "logger", getLogger(ctx))
-	// input ReadCloser will be auto-closed at the end of the httphandlefunc. We need to copy it.
-	rCopyBytes, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
 	newRW :=  ioutil.NopCloser(bytes.NewBuffer(rCopyBytes))
-	err = i.createAndPushNewConversionTask(asyncContext, newRW, id).RunAsync()
+	err = i.createAndPushNewConversionTask(asyncContext, newRW, id, format, opts).RunAsync()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return &id, nil
+	return &id, format, nil
 }
 
-func (i *Service) NewASCIIImageSync(ctx context.Context, r io.ReadCloser) (*uuid.UUID, error) {
+// NewASCIIImageSync converts the image and returns the new image's id and detected format once the image is stored
+func (i *Service) NewASCIIImageSync(ctx context.Context, r io.ReadCloser, opts ConversionOptions) (*uuid.UUID, string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, "", err
+	}
+	imageBytes, format, err := readImage(r)
+	if err != nil {
+		return nil, "", err
+	}
 	id := uuid.New()
-	task := i.createAndPushNewConversionTask(ctx, r, id)
+	task := i.createAndPushNewConversionTask(ctx, ioutil.NopCloser(bytes.NewReader(imageBytes)), id, format, opts)
 	result, err := task.Result()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, "", result.Error
 	}
-	return &id, nil
+	// go-async reports workers that exit because their context was cancelled through RunnerError rather than Error
+	if result.RunnerError != nil {
+		return nil, "", InternalProcessingError{fmt.Errorf("image processing cancelled: %w", result.RunnerError)}
+	}
+	return &id, format, nil
 }
 
-func (i *Service) createAndPushNewConversionTask(ctx context.Context, r io.ReadCloser, id uuid.UUID) *async.Task {
+func (i *Service) createAndPushNewConversionTask(ctx context.Context, r io.ReadCloser, id uuid.UUID, format string, opts ConversionOptions) *async.Task {
 	worker := func(_ context.Context) (async.T, error) {
 		logger := getLogger(ctx)
 
@@ -73,45 +97,118 @@ func (i *Service) createAndPushNewConversionTask(ctx context.Context, r io.ReadC
 			return nil, InternalProcessingError{fmt.Errorf("context cancelled")}
 		}
 
-		// step1. decode png
-		logger.Infof("decoding image %s", id)
+		// step1. decode image
+		logger.Infof("decoding %s image %s", format, id)
 		defer r.Close()
-		m, _, err := image.Decode(r)
+		data, err := ioutil.ReadAll(r)
 		if err != nil {
+			return nil, InternalProcessingError{fmt.Errorf("reading image: %w", err)}
+		}
+		// ansi art is already rendered, it's stored as is
+		if format == FormatANS {
+			asciiImage, err := importANS(data, opts)
+			if err != nil {
+				return nil, err
+			}
+			return i.store(logger, asciiImage, id)
+		}
+		var anim *animation
+		if opts.Animate {
+			if anim, err = decodeAnimation(data, format); err != nil {
+				// animations that are too large are rejected before they're decoded
+				if _, k := err.(InvalidInputError); k {
+					return nil, err
+				}
+				logger.Errorf("decoding animation failed: %s", err)
+				return nil, fmt.Errorf("error processing %s animation: %w", format, ImageProcessingError)
+			}
+		}
+		var m image.Image
+		if anim != nil {
+			logger.Infof("decoded %d animation frames", len(anim.frames))
+			m = anim.frames[0]
+		} else if m, _, err = image.Decode(bytes.NewReader(data)); err != nil {
 			logger.Errorf("decoding image failed: %s", err)
-			return nil, fmt.Errorf("error processing png image: %w", ImageProcessingError)
+			return nil, fmt.Errorf("error processing %s image: %w", format, ImageProcessingError)
 		}
 
 		if isContextCancelled(ctx) {
 			return nil, InternalProcessingError{fmt.Errorf("context cancelled")}
 		}
 
-		// step2: convert to ascii string
-		logger.Infof("converting image %s to ascii", id)
-		convertOpts := convert.Options{
-			Ratio:       1,
-			FixedWidth:  -1,
-			FixedHeight: -1,
-			FitScreen:   false,
+		// step2: preprocess the image
+		orientation := exifOrientation(data, format)
+		if orientation != orientationNormal && !opts.IgnoreOrientation {
+			logger.Infof("correcting exif orientation %d of image %s", orientation, id)
 		}
-		image := i.imageConverter.Image2ASCIIString(m, &convertOpts)
-
-		if isContextCancelled(ctx) {
-			return nil, InternalProcessingError{fmt.Errorf("context cancelled")}
+		if len(opts.Adjustments) > 0 {
+			logger.Infof("applying adjustments %v to image %s", opts.Adjustments, id)
+		}
+		if m, err = preprocess(ctx, logger, m, orientation, opts); err != nil {
+			return nil, err
 		}
 
-		// step3: push to image store
-		logger.Infof("storing image %s", id)
-		err = i.imageStore.PushASCIIImage(image, id)
+		// step3: convert to ascii string
+		if opts.Mode == "" {
+			opts.Mode = i.defaultMode
+		}
+		opts = opts.withDefaults()
+		converter, k := LookupConverter(opts.Mode)
+		if !k {
+			return nil, NewInvalidInputError(fmt.Errorf("unknown render mode %q", opts.Mode))
+		}
+		cellWidth, cellHeight := converter.CellSize()
+		width, height := opts.targetSize(m.Bounds(), cellWidth)
+		logger.Infof("converting image %s to ascii (%dx%d) with %s converter", id, width, height, opts.Mode)
+		if m, err = resample(ctx, m, width*cellWidth, height*cellHeight, opts.Resample); err != nil {
+			return nil, conversionError(ctx, logger, err)
+		}
+		grid, err := converter.Convert(ctx, m, width, height, opts)
 		if err != nil {
-			logger.Errorf("saving image failed: %s", err)
-			return nil, fmt.Errorf("error storing ascii image: %w", ImageStorageError)
+			return nil, conversionError(ctx, logger, err)
+		}
+		asciiImage := ASCIIImage{Value: grid.String(), SourceFormat: format, Options: opts, Cells: grid}
+		if opts.Color != ColorNone {
+			if asciiImage.ANSIValue, err = renderANSI(ctx, grid, opts); err != nil {
+				return nil, conversionError(ctx, logger, err)
+			}
+		}
+		if anim != nil {
+			asciiImage.LoopCount = anim.loopCount
+			for n, frame := range anim.frames {
+				if isContextCancelled(ctx) {
+					return nil, InternalProcessingError{fmt.Errorf("context cancelled")}
+				}
+				// the first frame has already been rendered above
+				if n > 0 {
+					if frame, err = preprocess(ctx, logger, frame, orientation, opts); err != nil {
+						return nil, err
+					}
+					if frame, err = resample(ctx, frame, width*cellWidth, height*cellHeight, opts.Resample); err != nil {
+						return nil, conversionError(ctx, logger, err)
+					}
+					if grid, err = converter.Convert(ctx, frame, width, height, opts); err != nil {
+						return nil, conversionError(ctx, logger, err)
+					}
+				}
+				asciiFrame := Frame{Value: grid.String(), Delay: anim.delays[n], Cells: grid}
+				if opts.Color != ColorNone {
+					if asciiFrame.ANSIValue, err = renderANSI(ctx, grid, opts); err != nil {
+						return nil, conversionError(ctx, logger, err)
+					}
+				}
+				asciiImage.Frames = append(asciiImage.Frames, asciiFrame)
+			}
+		}
+
+		if isContextCancelled(ctx) {
+			return nil, InternalProcessingError{fmt.Errorf("context cancelled")}
 		}
 
-		// step4: remove self from the asyncTask map
-		logger.Infof("processing successful")
-		delete(i.asyncTasks, id)
-		return id, nil
+		// step4: push to image store
+		// store the resolved dimensions so callers know the actual size the image was rendered at
+		asciiImage.Options.Width, asciiImage.Options.Height = width, height
+		return i.store(logger, asciiImage, id)
 	}
 
 	task := async.CreateTask(func() context.Context { return ctx }, async.WorkFn(worker))
@@ -119,7 +216,59 @@ func (i *Service) createAndPushNewConversionTask(ctx context.Context, r io.ReadC
 	return task
 }
 
-func (i *Service) GetASCIIImage(ctx context.Context, id uuid.UUID) (bool, []byte, error) {
+// store pushes a finished image to the image store and removes its task from the asyncTask map
+func (i *Service) store(logger *logrus.Entry, asciiImage ASCIIImage, id uuid.UUID) (async.T, error) {
+	logger.Infof("storing image %s", id)
+	err := i.imageStore.PushASCIIImage(asciiImage, id)
+	if err != nil {
+		logger.Errorf("saving image failed: %s", err)
+		return nil, fmt.Errorf("error storing ascii image: %w", ImageStorageError)
+	}
+
+	// step5: remove self from the asyncTask map
+	logger.Infof("processing successful")
+	delete(i.asyncTasks, id)
+	return id, nil
+}
+
+// preprocess transforms and adjusts a decoded image (or animation frame) before it's converted
+func preprocess(ctx context.Context, logger *logrus.Entry, m image.Image, orientation int, opts ConversionOptions) (image.Image, error) {
+	m, err := applyGeometry(ctx, m, orientation, opts)
+	if err != nil {
+		// a crop outside of the image can only be detected once it's decoded
+		if _, isInputError := err.(InvalidInputError); isInputError {
+			return nil, err
+		}
+		return nil, conversionError(ctx, logger, err)
+	}
+	if m, err = applyAlpha(ctx, m, opts); err != nil {
+		return nil, conversionError(ctx, logger, err)
+	}
+	if m, err = applyAdjustments(ctx, m, opts.Adjustments); err != nil {
+		return nil, conversionError(ctx, logger, err)
+	}
+	return m, nil
+}
+
+// conversionError maps an error returned by a Converter to the error the conversion task fails with
+func conversionError(ctx context.Context, logger *logrus.Entry, err error) error {
+	if isContextCancelled(ctx) {
+		return InternalProcessingError{fmt.Errorf("context cancelled")}
+	}
+	logger.Errorf("converting image failed: %s", err)
+	return fmt.Errorf("error converting image: %w", ImageProcessingError)
+}
+
+func (i *Service) GetASCIIImage(ctx context.Context, id uuid.UUID) (bool, *ASCIIImage, error) {
+	return i.getASCIIImage(ctx, id, i.imageStore.GetASCIIImage)
+}
+
+// GetASCIIImageCells is GetASCIIImage with the rendered cells of the image loaded, they're only needed by the cell formats
+func (i *Service) GetASCIIImageCells(ctx context.Context, id uuid.UUID) (bool, *ASCIIImage, error) {
+	return i.getASCIIImage(ctx, id, i.imageStore.GetASCIIImageCells)
+}
+
+func (i *Service) getASCIIImage(ctx context.Context, id uuid.UUID, get func(uuid.UUID) (bool, *ASCIIImage, error)) (bool, *ASCIIImage, error) {
 	logger := getLogger(ctx)
 	logger.Infof("attempting to fetch ascii image for imageID = %s", id)
 	processingTask, k := i.asyncTasks[id]
@@ -142,7 +291,7 @@ func (i *Service) GetASCIIImage(ctx context.Context, id uuid.UUID) (bool, []byte
 		}
 	}
 	logger.Infof("grabbing image from image store")
-	exists, image, err := i.imageStore.GetASCIIImage(id)
+	exists, image, err := get(id)
 	if err != nil {
 		return false, nil, err
 	}
@@ -151,7 +300,7 @@ func (i *Service) GetASCIIImage(ctx context.Context, id uuid.UUID) (bool, []byte
 		return false, nil, NewResourceNotFoundError(fmt.Errorf("image %s does not exist", id.String()))
 	}
 	logger.Infof("found image from image store")
-	return true, []byte(image), nil
+	return true, image, nil
 }
 
 func (i *Service) GetImageList(ctx context.Context) ([]uuid.UUID, error) {
@@ -0,0 +1,102 @@
+package image
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// nativeConverter maps the image onto a character ramp like image2asciiConverter, but does all the work itself
+// both copying the source image and computing cells are split into row bands spread across goroutines,
+// so large images convert faster and a cancelled context stops the conversion between bands
+// each cell is the average of the source pixels it covers (a box filter)
+type nativeConverter struct{}
+
+func (nativeConverter) CellSize() (int, int) {
+	return 1, 1
+}
+
+func (nativeConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	src, err := toRGBA(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	grid := make(Grid, height)
+	err = forEachRow(ctx, height, func(row int) {
+		cells := make([]Cell, width)
+		for col := range cells {
+			cells[col] = Cell{Color: averageColor(src, cellBounds(src.Bounds(), col, row, width, height))}
+		}
+		grid[row] = cells
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = applyRamp(ctx, grid, opts); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// toRGBA copies m into an *image.RGBA (with pixel access that's much faster than image.Image.At) band by band
+func toRGBA(ctx context.Context, m image.Image) (*image.RGBA, error) {
+	if rgba, k := m.(*image.RGBA); k {
+		return rgba, nil
+	}
+	bounds := m.Bounds()
+	dst := image.NewRGBA(bounds)
+	err := forEachRow(ctx, bounds.Dy(), func(row int) {
+		y := bounds.Min.Y + row
+		line := image.Rect(bounds.Min.X, y, bounds.Max.X, y+1)
+		draw.Draw(dst, line, m, line.Min, draw.Src)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// cellBounds is the area of the source image covered by the cell at (col, row) of a width x height grid
+// every cell covers at least one pixel, even when scaling up
+func cellBounds(src image.Rectangle, col, row, width, height int) image.Rectangle {
+	x0 := src.Min.X + col*src.Dx()/width
+	x1 := src.Min.X + (col+1)*src.Dx()/width
+	y0 := src.Min.Y + row*src.Dy()/height
+	y1 := src.Min.Y + (row+1)*src.Dy()/height
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	return image.Rect(x0, y0, x1, y1).Intersect(src)
+}
+
+// averageColor is the average of all the pixels in r
+// averaging premultiplied values keeps transparent pixels from bleeding their color into the result
+func averageColor(src *image.RGBA, r image.Rectangle) color.NRGBA {
+	var red, green, blue, alpha, n uint64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		offset := src.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			red += uint64(src.Pix[offset])
+			green += uint64(src.Pix[offset+1])
+			blue += uint64(src.Pix[offset+2])
+			alpha += uint64(src.Pix[offset+3])
+			offset += 4
+			n++
+		}
+	}
+	if n == 0 || alpha == 0 {
+		return color.NRGBA{}
+	}
+	// un-premultiply: channel sums are scaled by alpha/255 per pixel, so divide by the alpha sum rather than n
+	return color.NRGBA{
+		R: uint8(red * 255 / alpha),
+		G: uint8(green * 255 / alpha),
+		B: uint8(blue * 255 / alpha),
+		A: uint8(alpha / n),
+	}
+}
@@ -0,0 +1,122 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNativeConverter(t *testing.T) {
+	// left half black, right half white
+	m := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	for x := 4; x < 8; x++ {
+		for y := 0; y < 4; y++ {
+			m.Set(x, y, color.White)
+		}
+	}
+	converter, _ := LookupConverter(ModeNative)
+
+	grid, err := converter.Convert(context.Background(), m, 2, 2, ConversionOptions{Ramp: " @"})
+	assert.NoError(t, err)
+	assert.Equal(t, " @\n @\n", grid.String())
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, grid[0][1].Color)
+
+	grid, err = converter.Convert(context.Background(), m, 2, 2, ConversionOptions{Ramp: " @", Invert: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "@ \n@ \n", grid.String())
+
+	// scaling up repeats pixels
+	grid, err = converter.Convert(context.Background(), m, 16, 1, ConversionOptions{Ramp: " @"})
+	assert.NoError(t, err)
+	assert.Equal(t, "        @@@@@@@@\n", grid.String())
+}
+
+func TestAverageColor_Transparent(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	m.Set(1, 0, color.NRGBA{B: 255, A: 0})
+
+	// the fully transparent pixel only contributes to alpha, not color
+	assert.Equal(t, color.NRGBA{R: 255, A: 127}, averageColor(m, m.Bounds()))
+	assert.Equal(t, color.NRGBA{}, averageColor(m, image.Rect(1, 0, 2, 1)))
+}
+
+func TestForEachRow(t *testing.T) {
+	var seen [1000]int32
+	err := forEachRow(context.Background(), len(seen), func(row int) {
+		atomic.AddInt32(&seen[row], 1)
+	})
+	assert.NoError(t, err)
+	for row, n := range seen {
+		assert.Equal(t, int32(1), n, row)
+	}
+}
+
+func TestForEachRow_CancelledMidway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var processed int32
+	rows := 100000
+
+	err := forEachRow(ctx, rows, func(row int) {
+		atomic.AddInt32(&processed, 1)
+		cancel()
+	})
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	// bands that already started are finished, but no new ones are started
+	assert.Less(t, int(processed), rows)
+}
+
+func TestService_NewASCIIImageSyncE2E_Native(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Mode: ModeNative, Width: 8, Color: ColorTrue})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, ModeNative, asciiImage.Options.Mode)
+	assert.Equal(t, DefaultRamp, asciiImage.Options.Ramp)
+	assert.Equal(t, 8, asciiImage.Options.Width)
+	assert.Equal(t, 4, asciiImage.Options.Height)
+	assert.NotEmpty(t, asciiImage.ANSIValue)
+}
+
+func loadBenchmarkImage(b *testing.B) image.Image {
+	f, err := os.Open("../../test/data/worldview_3.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	m, _, err := image.Decode(f)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+func benchmarkConverter(b *testing.B, mode RenderMode, width int) {
+	m := loadBenchmarkImage(b)
+	converter, _ := LookupConverter(mode)
+	opts := ConversionOptions{Mode: mode, Width: width}.withDefaults()
+	cellWidth, _ := converter.CellSize()
+	width, height := opts.targetSize(m.Bounds(), cellWidth)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := converter.Convert(context.Background(), m, width, height, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// width 0 renders the image at its original size
+func BenchmarkConvert_ASCII_200(b *testing.B)   { benchmarkConverter(b, ModeASCII, 200) }
+func BenchmarkConvert_Native_200(b *testing.B)  { benchmarkConverter(b, ModeNative, 200) }
+func BenchmarkConvert_ASCII_Full(b *testing.B)  { benchmarkConverter(b, ModeASCII, 0) }
+func BenchmarkConvert_Native_Full(b *testing.B) { benchmarkConverter(b, ModeNative, 0) }
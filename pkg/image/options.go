@@ -0,0 +1,280 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultCellAspect is the width/height ratio of a character cell in most terminal fonts
+	DefaultCellAspect = 0.5
+	// MaxCellAspect is the widest character cell (relative to its height) that can be targeted
+	MaxCellAspect = 4.0
+	// MaxDimension is the largest width/height (in characters) an image can be rendered at
+	MaxDimension = 2000
+	// MaxScale is the largest scale factor that can be applied to an image's original dimensions
+	MaxScale = 4.0
+)
+
+// ColorMode selects whether and how an ANSI colored rendition of an image is generated
+type ColorMode string
+
+const (
+	ColorNone ColorMode = ""
+	Color16   ColorMode = "16"
+	Color256  ColorMode = "256"
+	ColorTrue ColorMode = "truecolor"
+)
+
+// ParseColorMode parses a user supplied color mode, "none" and "" both mean no color
+func ParseColorMode(value string) (ColorMode, error) {
+	switch mode := ColorMode(value); mode {
+	case ColorNone, Color16, Color256, ColorTrue:
+		return mode, nil
+	case "none":
+		return ColorNone, nil
+	}
+	return ColorNone, NewInvalidInputError(fmt.Errorf("unknown color mode %q, must be one of none, %s, %s, %s", value, Color16, Color256, ColorTrue))
+}
+
+// RenderMode is the name of the Converter an image is converted with
+type RenderMode string
+
+// names of the built-in converters
+const (
+	// ModeASCII maps pixels onto a character ramp, this is the default
+	ModeASCII RenderMode = "ascii"
+	// ModeBraille maps 2x4 pixel blocks onto braille characters
+	ModeBraille RenderMode = "braille"
+	// ModePixels maps 1x2 pixel blocks onto half block characters colored with separate foreground and background colors
+	ModePixels RenderMode = "pixels"
+	// ModeEdges draws the edges of the image with characters that follow the edge's direction
+	ModeEdges RenderMode = "edges"
+	// ModeNative maps pixels onto a character ramp like ModeASCII, converting row bands in parallel and stopping as soon as the request is cancelled
+	ModeNative RenderMode = "native"
+	// ModeEmoji maps the color of every cell onto the closest emoji of an EmojiPalette
+	ModeEmoji RenderMode = "emoji"
+)
+
+// ParseRenderMode parses a user supplied render mode, which must be the name of a registered Converter
+// "" is left as is so the service's default mode is used
+func ParseRenderMode(value string) (RenderMode, error) {
+	mode := RenderMode(value)
+	if mode == "" {
+		return mode, nil
+	}
+	if _, k := LookupConverter(mode); !k {
+		return "", NewInvalidInputError(fmt.Errorf("unknown render mode %q, must be one of %s", value, renderModeList()))
+	}
+	return mode, nil
+}
+
+// ConversionOptions controls how an image is rendered into ascii
+// zero values fall back to defaults, so the zero ConversionOptions renders the image at its original size
+type ConversionOptions struct {
+	// Mode is the name of the Converter the image is converted with. Defaults to the service's default mode
+	Mode RenderMode
+	// Width is the number of characters per line
+	Width int
+	// Height is the number of lines
+	Height int
+	// Scale is applied to the original image dimensions when neither Width nor Height are set
+	Scale float64
+	// Color additionally renders an ANSI colored version of the image
+	// ModePixels is always colored and defaults to ColorTrue
+	Color ColorMode
+	// ColorDither is how colors are snapped to the palette of Color16 and Color256, by default every color is snapped to its nearest palette color
+	ColorDither DitherMode
+	// Ramp is the characters pixels are mapped onto, ordered from darkest to brightest pixel. Defaults to DefaultRamp
+	Ramp string
+	// Invert walks the ramp from brightest to darkest pixel instead
+	Invert bool
+	// Animate renders every frame of an animated gif or png instead of just the first
+	Animate bool
+	// Threshold is the brightness (0-1) above which a pixel raises a dot in ModeBraille, or fills a half block in the monochrome rendition of ModePixels
+	// Defaults to DefaultThreshold when nil. In ModeEdges it's the gradient magnitude above which a cell is an edge, defaulting to DefaultEdgeThreshold
+	Threshold *float64
+	// Dither is how brightness is quantized onto the ramp's characters, or onto braille dots in ModeBraille
+	Dither DitherMode
+	// Adjustments are applied to the decoded image, in order, before it's converted
+	Adjustments []Adjustment
+	// Crop is the part of the image that's converted, relative to the image after exif orientation is corrected
+	Crop Crop
+	// Rotate rotates the image clockwise by 0, 90, 180 or 270 degrees after cropping
+	Rotate int
+	// Flip mirrors the image after rotating
+	Flip FlipMode
+	// IgnoreOrientation disables turning images upright according to their exif orientation
+	IgnoreOrientation bool
+	// Resample is the filter the image is scaled to the pixels its cells cover with, defaults to the converter's own resampling
+	Resample ResampleMode
+	// CellAspect is the width/height ratio of the character cells the image is displayed with, used to keep the image's aspect ratio
+	// Defaults to DefaultCellAspect, renderers with square cells (i.e html with a tuned line-height) should use 1
+	CellAspect float64
+	// Alpha is how transparent pixels are converted
+	Alpha AlphaMode
+	// Background is the color transparent pixels are drawn over with AlphaComposite, defaults to black
+	Background color.NRGBA
+	// AlphaThreshold is the opacity (0-1) below which pixels or cells count as transparent with AlphaSpace and AlphaThreshold
+	// Defaults to DefaultAlphaThreshold when nil
+	AlphaThreshold *float64
+	// EdgeDetector is the edge detection algorithm of ModeEdges, defaults to EdgeSobel
+	EdgeDetector EdgeDetector
+	// EdgeOverlay fills in the cells in between edges from the character ramp in ModeEdges
+	EdgeOverlay bool
+	// EmojiPalette is the emoji ModeEmoji maps cells onto, defaults to EmojiSquares
+	EmojiPalette EmojiPalette
+	// Title and Author describe the image, they aren't used for the conversion but are carried into exports (i.e .ans files)
+	Title  string
+	Author string
+}
+
+// Validate returns an InvalidInputError if any of the options are out of range
+func (o ConversionOptions) Validate() error {
+	if o.Width < 0 || o.Width > MaxDimension {
+		return NewInvalidInputError(fmt.Errorf("width must be at most %d, or 0 to derive it from the image", MaxDimension))
+	}
+	if o.Height < 0 || o.Height > MaxDimension {
+		return NewInvalidInputError(fmt.Errorf("height must be at most %d, or 0 to derive it from the image", MaxDimension))
+	}
+	if o.Scale < 0 || o.Scale > MaxScale || math.IsNaN(o.Scale) {
+		return NewInvalidInputError(fmt.Errorf("scale must be between 0 and %v", MaxScale))
+	}
+	if _, err := ParseColorMode(string(o.Color)); err != nil {
+		return err
+	}
+	if _, err := ParseDitherMode(string(o.ColorDither)); err != nil {
+		return err
+	}
+	if err := validateRamp(o.Ramp); err != nil {
+		return err
+	}
+	if _, err := ParseRenderMode(string(o.Mode)); err != nil {
+		return err
+	}
+	if o.Threshold != nil && (*o.Threshold < 0 || *o.Threshold >= 1 || math.IsNaN(*o.Threshold)) {
+		return NewInvalidInputError(fmt.Errorf("threshold must be between 0 and 1"))
+	}
+	if _, err := ParseDitherMode(string(o.Dither)); err != nil {
+		return err
+	}
+	if err := validateAdjustments(o.Adjustments); err != nil {
+		return err
+	}
+	if err := o.Crop.validate(); err != nil {
+		return err
+	}
+	if err := validateRotation(o.Rotate); err != nil {
+		return err
+	}
+	if _, err := ParseFlipMode(string(o.Flip)); err != nil {
+		return err
+	}
+	if _, err := ParseAlphaMode(string(o.Alpha)); err != nil {
+		return err
+	}
+	if o.AlphaThreshold != nil && (*o.AlphaThreshold < 0 || *o.AlphaThreshold > 1 || math.IsNaN(*o.AlphaThreshold)) {
+		return NewInvalidInputError(fmt.Errorf("alpha threshold must be between 0 and 1"))
+	}
+	if _, err := ParseEdgeDetector(string(o.EdgeDetector)); err != nil {
+		return err
+	}
+	if _, err := ParseResampleMode(string(o.Resample)); err != nil {
+		return err
+	}
+	if _, err := ParseEmojiPalette(string(o.EmojiPalette)); err != nil {
+		return err
+	}
+	if o.CellAspect < 0 || o.CellAspect > MaxCellAspect || math.IsNaN(o.CellAspect) {
+		return NewInvalidInputError(fmt.Errorf("aspect must be greater than 0 and at most %v", MaxCellAspect))
+	}
+	if utf8.RuneCountInString(o.Title) > MaxTitleLength {
+		return NewInvalidInputError(fmt.Errorf("title must be at most %d characters", MaxTitleLength))
+	}
+	if utf8.RuneCountInString(o.Author) > MaxAuthorLength {
+		return NewInvalidInputError(fmt.Errorf("author must be at most %d characters", MaxAuthorLength))
+	}
+	return nil
+}
+
+// threshold is the Threshold option, or defaultThreshold if it isn't set
+func (o ConversionOptions) threshold(defaultThreshold float64) float64 {
+	if o.Threshold == nil {
+		return defaultThreshold
+	}
+	return *o.Threshold
+}
+
+// withDefaults fills in unset options so the stored options describe exactly how an image was rendered
+func (o ConversionOptions) withDefaults() ConversionOptions {
+	if o.Mode == "" {
+		o.Mode = ModeASCII
+	}
+	// emoji are about square
+	if o.Mode == ModeEmoji && o.CellAspect == 0 {
+		o.CellAspect = 1
+	}
+	if o.CellAspect == 0 {
+		o.CellAspect = DefaultCellAspect
+	}
+	if o.Mode == ModeEmoji && o.EmojiPalette == "" {
+		o.EmojiPalette = EmojiSquares
+	}
+	if (o.Mode == ModeASCII || o.Mode == ModeNative || (o.Mode == ModeEdges && o.EdgeOverlay)) && o.Ramp == "" {
+		o.Ramp = DefaultRamp
+	}
+	if o.Mode == ModeEdges && o.EdgeDetector == "" {
+		o.EdgeDetector = EdgeSobel
+	}
+	// the whole point of pixels is color so it's always rendered colored
+	if o.Mode == ModePixels && o.Color == ColorNone {
+		o.Color = ColorTrue
+	}
+	return o
+}
+
+// targetSize computes the character grid (columns, rows) an image with the given bounds is rendered to
+// cellWidth is the number of pixel columns each character cell represents for the renderer
+// if only one of Width/Height is set, the other is derived so the image keeps its aspect ratio once displayed with cells of CellAspect
+// otherwise Scale is applied to the image's width, one pixel per cell column, and the height follows from the aspect ratio
+// neither dimension is ever larger than MaxDimension
+func (o ConversionOptions) targetSize(bounds image.Rectangle, cellWidth int) (int, int) {
+	srcWidth, srcHeight := float64(bounds.Dx()), float64(bounds.Dy())
+	aspect := o.CellAspect
+	if aspect == 0 {
+		aspect = DefaultCellAspect
+	}
+	var width, height float64
+	switch {
+	case o.Width > 0 && o.Height > 0:
+		width, height = float64(o.Width), float64(o.Height)
+	case o.Width > 0:
+		width = float64(o.Width)
+		height = width * aspect * srcHeight / srcWidth
+	case o.Height > 0:
+		height = float64(o.Height)
+		width = height / aspect * srcWidth / srcHeight
+	default:
+		scale := o.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		width = srcWidth * scale / float64(cellWidth)
+		height = width * aspect * srcHeight / srcWidth
+	}
+	// derived sizes of large images (or tall/wide ones) are scaled down to fit MaxDimension, keeping the aspect ratio
+	if largest := math.Max(width, height); largest > MaxDimension {
+		width, height = width*MaxDimension/largest, height*MaxDimension/largest
+	}
+	return roundDimension(width), roundDimension(height)
+}
+
+func roundDimension(d float64) int {
+	if n := int(math.Round(d)); n > 1 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,293 @@
+package image
+
+import (
+	"context"
+	"image/color"
+	"math"
+)
+
+// lab is a color in the CIELAB color space, where euclidean distance roughly matches how different colors look
+type lab struct {
+	l, a, b float64
+}
+
+// toLab converts an sRGB color to CIELAB under the D65 white point
+func toLab(c color.NRGBA) lab {
+	r, g, b := linearize(c.R), linearize(c.G), linearize(c.B)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return lab{l: 116*fy - 16, a: 500 * (fx - fy), b: 200 * (fy - fz)}
+}
+
+// linearize undoes the sRGB gamma curve
+func linearize(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+func (c lab) sqDistance(o lab) float64 {
+	dl, da, db := c.l-o.l, c.a-o.a, c.b-o.b
+	return dl*dl + da*da + db*db
+}
+
+// Palette is a fixed set of colors that colors are matched to
+// colors are matched by CIELAB distance, so the nearest color is the one that looks closest rather than the one with the closest RGB values
+type Palette struct {
+	colors []color.NRGBA
+	labs   []lab
+}
+
+// NewPalette returns the palette of the given colors
+func NewPalette(colors ...color.NRGBA) *Palette {
+	p := &Palette{colors: colors, labs: make([]lab, len(colors))}
+	for n, c := range colors {
+		p.labs[n] = toLab(c)
+	}
+	return p
+}
+
+// Nearest returns the index of the palette color closest to c
+func (p *Palette) Nearest(c color.NRGBA) int {
+	target := toLab(c)
+	nearest, nearestDistance := 0, math.Inf(1)
+	for n, l := range p.labs {
+		if distance := target.sqDistance(l); distance < nearestDistance {
+			nearest, nearestDistance = n, distance
+		}
+	}
+	return nearest
+}
+
+// colorPalette is the palette of a terminal color mode
+type colorPalette struct {
+	*Palette
+	// offset is the terminal color index of the first color
+	offset int
+	// spread is how far (in 0-255 channel values) ordered dithering pushes colors, about the distance between neighbouring colors
+	spread float64
+}
+
+func newPalette(colors []color.NRGBA, offset int, spread float64) *colorPalette {
+	return &colorPalette{Palette: NewPalette(colors...), offset: offset, spread: spread}
+}
+
+var (
+	// palette16 are the 16 standard terminal colors
+	palette16 = newPalette(ansi16Colors[:], 0, 128)
+	// palette256 is the color cube and grayscale ramp of the xterm 256 color palette
+	// the first 16 colors are left out since they vary between terminals
+	palette256 = newPalette(xterm256Colors(), 16, 40)
+)
+
+func xterm256Colors() []color.NRGBA {
+	colors := make([]color.NRGBA, 0, 240)
+	for n := 16; n < 256; n++ {
+		colors = append(colors, xterm256Color(n))
+	}
+	return colors
+}
+
+// paletteFor returns the palette of a color mode, nil for modes that aren't limited to a palette
+func paletteFor(mode ColorMode) *colorPalette {
+	switch mode {
+	case Color16:
+		return palette16
+	case Color256:
+		return palette256
+	}
+	return nil
+}
+
+// nearest returns the terminal color index of the palette color closest to c
+func (p *colorPalette) nearest(c color.NRGBA) int {
+	return p.Nearest(c) + p.offset
+}
+
+func (p *colorPalette) color(index int) color.NRGBA {
+	return p.colors[index-p.offset]
+}
+
+// colorLimit ranks color modes by how many colors they can show
+func colorLimit(mode ColorMode) int {
+	switch mode {
+	case ColorNone:
+		return 0
+	case Color16:
+		return 16
+	case Color256:
+		return 256
+	}
+	return 1 << 24
+}
+
+// DitherColors returns a copy of the grid with its foreground and background colors snapped to the palette of mode using the given dither mode
+// transparent cells and cells without a color of their own are left alone
+// the grid is returned as is for modes without a palette or without dithering, ANSI picks the nearest palette colors itself
+func (g Grid) DitherColors(ctx context.Context, mode ColorMode, dither DitherMode) (Grid, error) {
+	p := paletteFor(mode)
+	if p == nil || dither == DitherNone {
+		return g, nil
+	}
+	return p.snapColors(ctx, g, dither)
+}
+
+// snapColors returns a copy of the grid with its foreground and background colors snapped to the palette using the given dither mode
+func (p *colorPalette) snapColors(ctx context.Context, g Grid, dither DitherMode) (Grid, error) {
+	if g == nil {
+		return nil, nil
+	}
+	dithered := make(Grid, len(g))
+	for y, row := range g {
+		dithered[y] = append([]Cell(nil), row...)
+	}
+	// foregrounds and backgrounds are dithered separately since they're separate layers of the image
+	err := p.dither(ctx, dithered, dither, func(cell *Cell) *color.NRGBA {
+		if cell.Transparent || cell.Color.A == 0 {
+			return nil
+		}
+		return &cell.Color
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = p.dither(ctx, dithered, dither, func(cell *Cell) *color.NRGBA {
+		if cell.Transparent || cell.Background == nil {
+			return nil
+		}
+		background := *cell.Background
+		cell.Background = &background
+		return cell.Background
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dithered, nil
+}
+
+// dither snaps the color layer returns of every cell to the palette in place
+// error diffusion spreads the difference between each color and its palette color onto the cells that follow, ordered dithering offsets every color by the bayer matrix
+func (p *colorPalette) dither(ctx context.Context, grid Grid, mode DitherMode, layer func(cell *Cell) *color.NRGBA) error {
+	kernel := diffusionKernels[mode]
+	// the error pushed onto every cell so far, per channel
+	errs := make([][][3]float64, len(grid))
+	for y, row := range grid {
+		errs[y] = make([][3]float64, len(row))
+	}
+	for y, row := range grid {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for x := range row {
+			c := layer(&row[x])
+			if c == nil {
+				continue
+			}
+			value := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+			for channel := range value {
+				if mode == DitherBayer {
+					value[channel] += ((bayer4[y%4][x%4]+0.5)/16 - 0.5) * p.spread
+				} else {
+					value[channel] += errs[y][x][channel]
+				}
+				value[channel] = math.Max(0, math.Min(255, value[channel]))
+			}
+			snapped := p.color(p.nearest(color.NRGBA{R: uint8(math.Round(value[0])), G: uint8(math.Round(value[1])), B: uint8(math.Round(value[2])), A: 255}))
+			quantErr := [3]float64{value[0] - float64(snapped.R), value[1] - float64(snapped.G), value[2] - float64(snapped.B)}
+			snapped.A = c.A
+			*c = snapped
+			for _, d := range kernel {
+				nx, ny := x+d.dx, y+d.dy
+				if ny < len(grid) && nx >= 0 && nx < len(grid[ny]) {
+					for channel := range quantErr {
+						errs[ny][nx][channel] += quantErr[channel] * d.weight
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renderANSI renders the colored rendition of the grid in opts.Color, snapping colors to its palette with opts.ColorDither
+func renderANSI(ctx context.Context, grid Grid, opts ConversionOptions) (string, error) {
+	dithered, err := grid.DitherColors(ctx, opts.Color, opts.ColorDither)
+	if err != nil {
+		return "", err
+	}
+	return dithered.ANSI(opts.Color), nil
+}
+
+// Downsample returns a copy of the image with its colors snapped to the palette of mode, dithered with dither
+// the cells (including every frame's) keep the colors they were rendered from so they're snapped for any mode with a palette,
+// the colored renditions are only re-rendered if mode has fewer colors than the image was rendered in
+// the image is returned as is if there's nothing to snap
+func (a *ASCIIImage) Downsample(ctx context.Context, mode ColorMode, dither DitherMode) (*ASCIIImage, error) {
+	p := paletteFor(mode)
+	rerender := a.ANSIValue != "" && colorLimit(mode) < colorLimit(a.Options.Color)
+	if p == nil || !rerender && !a.hasCells() {
+		return a, nil
+	}
+	downsampled := *a
+	var err error
+	if rerender {
+		downsampled.Options.Color, downsampled.Options.ColorDither = mode, dither
+		if downsampled.ANSIValue, err = downsampleANSI(ctx, a.ANSIValue, downsampled.Options); err != nil {
+			return nil, err
+		}
+	}
+	if downsampled.Cells, err = p.snapColors(ctx, a.Cells, dither); err != nil {
+		return nil, err
+	}
+	downsampled.Frames = make([]Frame, len(a.Frames))
+	for n, frame := range a.Frames {
+		if rerender {
+			if frame.ANSIValue, err = downsampleANSI(ctx, frame.ANSIValue, downsampled.Options); err != nil {
+				return nil, err
+			}
+		}
+		if frame.Cells, err = p.snapColors(ctx, frame.Cells, dither); err != nil {
+			return nil, err
+		}
+		downsampled.Frames[n] = frame
+	}
+	return &downsampled, nil
+}
+
+func (a *ASCIIImage) hasCells() bool {
+	if a.Cells != nil {
+		return true
+	}
+	for _, frame := range a.Frames {
+		if frame.Cells != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// downsampleANSI re-renders a colored rendition with opts.Color and opts.ColorDither
+func downsampleANSI(ctx context.Context, text string, opts ConversionOptions) (string, error) {
+	grid := ParseANSI(text)
+	for _, row := range grid {
+		for x := range row {
+			// cells that had no colors at all keep the terminal's colors
+			if row[x].Color.A == 0 && row[x].Background == nil {
+				row[x].Transparent = true
+			}
+		}
+	}
+	return renderANSI(ctx, grid, opts)
+}
@@ -0,0 +1,167 @@
+package image
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestToLab(t *testing.T) {
+	white := toLab(color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	assert.InDelta(t, 100, white.l, 0.01)
+	assert.InDelta(t, 0, white.a, 0.01)
+	assert.InDelta(t, 0, white.b, 0.01)
+	black := toLab(color.NRGBA{A: 255})
+	assert.InDelta(t, 0, black.l, 0.01)
+	red := toLab(color.NRGBA{R: 255, A: 255})
+	assert.InDelta(t, 53.24, red.l, 0.01)
+	assert.InDelta(t, 80.09, red.a, 0.01)
+	assert.InDelta(t, 67.20, red.b, 0.01)
+}
+
+func TestPaletteNearest(t *testing.T) {
+	assert.Equal(t, 17, palette256.nearest(color.NRGBA{R: 0, G: 0, B: 60, A: 255}))
+	assert.Equal(t, 9, palette16.nearest(color.NRGBA{R: 250, G: 10, B: 10, A: 255}))
+	// every palette color is its own nearest color
+	for n := 0; n < 16; n++ {
+		assert.Equal(t, n, palette16.nearest(ansi16Colors[n]), "%d", n)
+	}
+}
+
+func TestNewPalette(t *testing.T) {
+	p := NewPalette(color.NRGBA{A: 255}, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, color.NRGBA{R: 255, A: 255})
+
+	assert.Equal(t, 0, p.Nearest(color.NRGBA{R: 30, G: 30, B: 30, A: 255}))
+	assert.Equal(t, 1, p.Nearest(color.NRGBA{R: 220, G: 230, B: 240, A: 255}))
+	assert.Equal(t, 2, p.Nearest(color.NRGBA{R: 200, G: 40, B: 20, A: 255}))
+}
+
+func TestGridANSI_16Colors(t *testing.T) {
+	blue := color.NRGBA{B: 220, A: 255}
+	grid := Grid{{{Char: 'a', Color: color.NRGBA{R: 255, A: 255}}, {Char: upperHalfBlock, Color: color.NRGBA{R: 200, A: 255}, Background: &blue, ColorChar: upperHalfBlock}}}
+
+	assert.Equal(t, "\x1b[91ma\x1b[31m\x1b[44m▀\x1b[0m\n", grid.ANSI(Color16))
+	// the 16 colors parse back to the standard colors
+	parsed := ParseANSI(grid.ANSI(Color16))
+	assert.Equal(t, ansi16Colors[9], parsed[0][0].Color)
+	assert.Equal(t, ansi16Colors[4], *parsed[0][1].Background)
+}
+
+func TestGridDitherColors(t *testing.T) {
+	gray := color.NRGBA{R: 160, G: 160, B: 160, A: 255}
+	grid := make(Grid, 8)
+	for y := range grid {
+		grid[y] = make([]Cell, 8)
+		for x := range grid[y] {
+			grid[y][x] = Cell{Char: '@', Color: gray}
+		}
+	}
+
+	undithered, err := grid.DitherColors(context.Background(), Color16, DitherNone)
+	assert.NoError(t, err)
+	assert.Equal(t, grid, undithered)
+
+	for _, mode := range []DitherMode{DitherFloydSteinberg, DitherAtkinson, DitherBayer} {
+		dithered, err := grid.DitherColors(context.Background(), Color16, mode)
+		assert.NoError(t, err, mode)
+		// the source grid is left alone
+		assert.Equal(t, gray, grid[0][0].Color, mode)
+		// a gray in between two palette colors is drawn as a mix of them that averages out close to it
+		used := map[color.NRGBA]bool{}
+		var sum float64
+		for _, row := range dithered {
+			for _, cell := range row {
+				used[cell.Color] = true
+				sum += float64(cell.Color.R)
+			}
+		}
+		assert.Greater(t, len(used), 1, mode)
+		assert.InDelta(t, 160, sum/64, 20, mode)
+		for c := range used {
+			assert.Contains(t, ansi16Colors[:], c, mode)
+		}
+	}
+}
+
+func TestASCIIImage_Downsample(t *testing.T) {
+	grid := Grid{
+		{{Char: '@', Color: color.NRGBA{R: 250, G: 10, B: 10, A: 255}}, {Char: ' ', Transparent: true}},
+	}
+	asciiImage := &ASCIIImage{
+		Value:     grid.String(),
+		ANSIValue: grid.ANSI(ColorTrue),
+		Options:   ConversionOptions{Color: ColorTrue},
+		Frames:    []Frame{{Value: grid.String(), ANSIValue: grid.ANSI(ColorTrue)}},
+	}
+
+	downsampled, err := asciiImage.Downsample(context.Background(), Color16, DitherNone)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[91m@ \x1b[0m\n", downsampled.ANSIValue)
+	assert.Equal(t, downsampled.ANSIValue, downsampled.Frames[0].ANSIValue)
+	assert.Equal(t, Color16, downsampled.Options.Color)
+	// the stored image is left alone
+	assert.Equal(t, grid.ANSI(ColorTrue), asciiImage.ANSIValue)
+	assert.Equal(t, grid.ANSI(ColorTrue), asciiImage.Frames[0].ANSIValue)
+
+	// images can't be upsampled
+	same, err := downsampled.Downsample(context.Background(), Color256, DitherNone)
+	assert.NoError(t, err)
+	assert.Same(t, downsampled, same)
+	same, err = asciiImage.Downsample(context.Background(), ColorNone, DitherNone)
+	assert.NoError(t, err)
+	assert.Same(t, asciiImage, same)
+}
+
+func TestASCIIImage_DownsampleCells(t *testing.T) {
+	navy := color.NRGBA{R: 20, G: 30, B: 90, A: 255}
+	grid := Grid{
+		{{Char: '@', Color: color.NRGBA{R: 250, G: 10, B: 10, A: 255}, Background: &navy}, {Char: '#', Color: color.NRGBA{R: 100, G: 200, B: 120, A: 255}}, {Char: ' ', Transparent: true}},
+	}
+	// cells keep the colors they were rendered from, even for images rendered in 16 colors
+	asciiImage := &ASCIIImage{
+		Value:     grid.String(),
+		ANSIValue: grid.ANSI(Color16),
+		Options:   ConversionOptions{Color: Color16},
+		Cells:     grid,
+		Frames:    []Frame{{Value: grid.String(), ANSIValue: grid.ANSI(Color16), Cells: grid}},
+	}
+
+	for _, dither := range []DitherMode{DitherNone, DitherFloydSteinberg} {
+		downsampled, err := asciiImage.Downsample(context.Background(), Color16, dither)
+
+		assert.NoError(t, err)
+		for _, cells := range []Grid{downsampled.Cells, downsampled.Frames[0].Cells} {
+			for _, cell := range cells[0][:2] {
+				assert.Contains(t, ansi16Colors, cell.Color, dither)
+			}
+			assert.Contains(t, ansi16Colors, *cells[0][0].Background, dither)
+			assert.Equal(t, Cell{Char: ' ', Transparent: true}, cells[0][2], dither)
+		}
+		// the rendition already is in 16 colors
+		assert.Equal(t, asciiImage.ANSIValue, downsampled.ANSIValue)
+	}
+	// the stored cells are left alone
+	assert.Equal(t, color.NRGBA{R: 250, G: 10, B: 10, A: 255}, asciiImage.Cells[0][0].Color)
+	assert.Equal(t, navy, *asciiImage.Frames[0].Cells[0][0].Background)
+}
+
+func TestService_NewASCIIImageSyncE2E_16Colors(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 16, Color: Color16, ColorDither: DitherFloydSteinberg})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, DitherFloydSteinberg, asciiImage.Options.ColorDither)
+	assert.NotEmpty(t, asciiImage.ANSIValue)
+	// only the 16 color codes are used
+	for _, escape := range regexp.MustCompile(`\x1b\[[0-9;]*m`).FindAllString(asciiImage.ANSIValue, -1) {
+		assert.False(t, strings.Contains(escape, ";"), escape)
+	}
+}
@@ -0,0 +1,78 @@
+package image
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// half block characters
+const (
+	upperHalfBlock = '▀'
+	lowerHalfBlock = '▄'
+	fullBlock      = '█'
+)
+
+// pixelsConverter maps every 1x2 pixel block onto an upper half block
+// the foreground colors the top pixel and the background colors the bottom pixel, so every cell shows two real pixels
+// the monochrome rendition falls back to thresholded half/full blocks since it has no colors to work with
+type pixelsConverter struct{}
+
+func (pixelsConverter) CellSize() (int, int) {
+	return 1, 2
+}
+
+func (pixelsConverter) Convert(ctx context.Context, m image.Image, width, height int, opts ConversionOptions) (Grid, error) {
+	scaled := resizeImage(m, width, height*2)
+	bounds := scaled.Bounds()
+	threshold := opts.threshold(DefaultThreshold)
+
+	grid := make(Grid, height)
+	for row := 0; row < height; row++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		grid[row] = make([]Cell, width)
+		for col := 0; col < width; col++ {
+			top := color.NRGBAModel.Convert(scaled.At(bounds.Min.X+col, bounds.Min.Y+row*2)).(color.NRGBA)
+			bottom := color.NRGBAModel.Convert(scaled.At(bounds.Min.X+col, bounds.Min.Y+row*2+1)).(color.NRGBA)
+			topLit := (opts.brightness(top) > threshold) != opts.Invert
+			bottomLit := (opts.brightness(bottom) > threshold) != opts.Invert
+			grid[row][col] = halfBlockCell(top, bottom, topLit, bottomLit, opts)
+		}
+	}
+	return grid, nil
+}
+
+// halfBlockCell colors the top pixel with the foreground and the bottom pixel with the background
+// transparent halves are left uncolored: the other half is drawn with the foreground alone so no background escape is needed
+func halfBlockCell(top, bottom color.NRGBA, topLit, bottomLit bool, opts ConversionOptions) Cell {
+	topTransparent, bottomTransparent := opts.transparent(top), opts.transparent(bottom)
+	switch {
+	case topTransparent && bottomTransparent:
+		return Cell{Char: ' ', Transparent: true}
+	case topTransparent:
+		return Cell{Char: halfBlock(false, bottomLit), Color: bottom, ColorChar: lowerHalfBlock, Luminance: opts.brightness(bottom)}
+	case bottomTransparent:
+		return Cell{Char: halfBlock(topLit, false), Color: top, ColorChar: upperHalfBlock, Luminance: opts.brightness(top)}
+	}
+	return Cell{
+		Char:       halfBlock(topLit, bottomLit),
+		Color:      top,
+		Background: &bottom,
+		ColorChar:  upperHalfBlock,
+		Luminance:  (opts.brightness(top) + opts.brightness(bottom)) / 2,
+	}
+}
+
+func halfBlock(top, bottom bool) rune {
+	switch {
+	case top && bottom:
+		return fullBlock
+	case top:
+		return upperHalfBlock
+	case bottom:
+		return lowerHalfBlock
+	}
+	return ' '
+}
@@ -0,0 +1,113 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultRamp is the character ramp used by image2ascii, ordered from darkest to brightest pixel
+const DefaultRamp = " .,:;i1tfLCG08@"
+
+const maxRampLength = 256
+
+// named built-in ramps, all ordered from darkest to brightest pixel
+var namedRamps = map[string]string{
+	"default":  DefaultRamp,
+	"simple":   " .:-=+*#%@",
+	"detailed": " .'`^\",:;Il!i><~+_-?][}{1)(|\\/tfjrxnuvczXYUJCLQ0OZmwqpdbkhao*#MW&8%B@$",
+	"blocks":   " ░▒▓█",
+	"digits":   "1742356980",
+}
+
+// ParseRamp resolves a user supplied ramp into the characters of the ramp
+// value is either the name of a built-in ramp or the literal ramp characters ordered from darkest to brightest pixel
+func ParseRamp(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if ramp, k := namedRamps[value]; k {
+		return ramp, nil
+	}
+	if err := validateRamp(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func validateRamp(ramp string) error {
+	if ramp == "" {
+		return nil
+	}
+	if !utf8.ValidString(ramp) {
+		return NewInvalidInputError(fmt.Errorf("ramp must be valid utf-8"))
+	}
+	length := utf8.RuneCountInString(ramp)
+	if length < 2 || length > maxRampLength {
+		return NewInvalidInputError(fmt.Errorf("ramp must be a built-in ramp name or between 2 and %d characters", maxRampLength))
+	}
+	for _, r := range ramp {
+		if !unicode.IsPrint(r) && r != ' ' {
+			return NewInvalidInputError(fmt.Errorf("ramp contains non-printable character %q", r))
+		}
+	}
+	return nil
+}
+
+// rampChar maps the brightness of a pixel onto a character of the ramp
+// when invert is set the ramp is walked from brightest to darkest, which suits light background terminals
+func rampChar(ramp []rune, invert bool, c color.NRGBA) rune {
+	return rampAt(ramp, invert, nearestLevel(len(ramp))(intensity(c)))
+}
+
+// rampAt is the character for a brightness level between 0 and len(ramp)-1
+func rampAt(ramp []rune, invert bool, level int) rune {
+	if invert {
+		level = len(ramp) - 1 - level
+	}
+	return ramp[level]
+}
+
+// applyRamp sets the character of every cell from the brightness of its color, dithered according to opts.Dither
+// transparent cells (see ConversionOptions.Alpha) become spaces
+func applyRamp(ctx context.Context, grid Grid, opts ConversionOptions) error {
+	ramp := []rune(opts.Ramp)
+	if len(ramp) == 0 {
+		ramp = []rune(DefaultRamp)
+	}
+	field := make([][]float64, len(grid))
+	for y, row := range grid {
+		field[y] = make([]float64, len(row))
+		for x, cell := range row {
+			field[y][x] = opts.brightness(cell.Color)
+			row[x].Luminance = field[y][x]
+			// transparent cells are left out of dithering so their brightness doesn't bleed into the cells around them
+			if opts.transparent(cell.Color) {
+				field[y][x] = math.NaN()
+			}
+		}
+	}
+	levels, err := quantize(ctx, field, len(ramp), opts.Dither, nearestLevel(len(ramp)))
+	if err != nil {
+		return err
+	}
+	for y, row := range grid {
+		for x := range row {
+			if opts.transparent(row[x].Color) {
+				row[x].Char, row[x].Transparent = ' ', true
+				continue
+			}
+			row[x].Char = rampAt(ramp, opts.Invert, levels[y][x])
+		}
+	}
+	return nil
+}
+
+// intensity is the brightness of a pixel between 0 and 1
+// same as image2ascii: the average of the channels, with transparent pixels treated as dark
+func intensity(c color.NRGBA) float64 {
+	return (float64(c.R) + float64(c.G) + float64(c.B)) * float64(c.A) / 255 / (255 * 3)
+}
@@ -0,0 +1,94 @@
+package image
+
+import (
+	"github.com/qeesung/image2ascii/ascii"
+	"image/color"
+	"strings"
+)
+
+// Cell is a single rendered character along with the color of the pixels it was rendered from
+type Cell struct {
+	Char  rune
+	Color color.NRGBA
+	// Background is the color behind the character, only set by renderers that color both
+	Background *color.NRGBA
+	// ColorChar replaces Char in colored renditions, for renderers that rely on the background color to draw
+	ColorChar rune
+	// Transparent cells are rendered as Char without any color escapes, so the terminal's own background shows through
+	Transparent bool
+	// Luminance is the brightness (0-1) of the pixels the cell was rendered from, as used to pick its character
+	Luminance float64
+}
+
+// Grid is a rendered image, one slice of cells per line
+type Grid [][]Cell
+
+// gridFromCharPixels converts image2ascii's pixel matrix into a Grid of colored cells
+// image2ascii's own character choice is discarded, characters are set afterwards by applyRamp
+func gridFromCharPixels(pixels [][]ascii.CharPixel) Grid {
+	grid := make(Grid, len(pixels))
+	for y, row := range pixels {
+		grid[y] = make([]Cell, len(row))
+		for x, pixel := range row {
+			grid[y][x] = Cell{Color: color.NRGBA{R: pixel.R, G: pixel.G, B: pixel.B, A: pixel.A}}
+		}
+	}
+	return grid
+}
+
+// String renders the grid as monochrome text, each line terminated by a newline
+func (g Grid) String() string {
+	var sb strings.Builder
+	for _, row := range g {
+		for _, cell := range row {
+			sb.WriteRune(cell.Char)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ANSI renders the grid as text colored with ANSI escape sequences for the given color mode
+// escapes are only emitted when the color changes and every line ends with a reset so lines can be printed on their own
+// ColorNone renders the same as String
+func (g Grid) ANSI(mode ColorMode) string {
+	if mode == ColorNone {
+		return g.String()
+	}
+	var sb strings.Builder
+	for _, row := range g {
+		// every line starts out with the terminal's default background
+		lastForeground, lastBackground := "", ansiDefaultBackground
+		for _, cell := range row {
+			// transparent cells keep the terminal's background and have nothing to color
+			if cell.Transparent {
+				if lastBackground != ansiDefaultBackground {
+					sb.WriteString(ansiDefaultBackground)
+					lastBackground = ansiDefaultBackground
+				}
+				sb.WriteRune(cell.Char)
+				continue
+			}
+			if escape := foregroundEscape(mode, cell.Color); escape != lastForeground {
+				sb.WriteString(escape)
+				lastForeground = escape
+			}
+			background := ansiDefaultBackground
+			if cell.Background != nil {
+				background = backgroundEscape(mode, *cell.Background)
+			}
+			if background != lastBackground {
+				sb.WriteString(background)
+				lastBackground = background
+			}
+			if cell.ColorChar != 0 {
+				sb.WriteRune(cell.ColorChar)
+			} else {
+				sb.WriteRune(cell.Char)
+			}
+		}
+		sb.WriteString(ansiReset)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"github.com/nfnt/resize"
+	"golang.org/x/image/draw"
+	"image"
+)
+
+// ResampleMode selects the filter an image is scaled down (or up) to the pixels its character cells cover with
+// the resampling filter decides which detail survives downscaling, so it drives how good the result looks as much as the character mapping does
+type ResampleMode string
+
+const (
+	// ResampleDefault leaves resampling to the converter: Lanczos for most, an area average for ModeNative and ModeEmoji
+	ResampleDefault ResampleMode = ""
+	// ResampleNearest picks the single source pixel closest to every target pixel, the fastest but it aliases badly when downscaling
+	ResampleNearest ResampleMode = "nearest"
+	// ResampleBox averages all the source pixels every target pixel covers
+	ResampleBox ResampleMode = "box"
+	// ResampleBilinear weighs source pixels by their distance to every target pixel with a tent filter
+	ResampleBilinear ResampleMode = "bilinear"
+	// ResampleLanczos weighs source pixels with a Lanczos3 windowed sinc, the sharpest and the slowest
+	ResampleLanczos ResampleMode = "lanczos"
+)
+
+// ParseResampleMode parses a user supplied resample mode, "" leaves the converter's own resampling
+func ParseResampleMode(value string) (ResampleMode, error) {
+	switch mode := ResampleMode(value); mode {
+	case ResampleDefault, ResampleNearest, ResampleBox, ResampleBilinear, ResampleLanczos:
+		return mode, nil
+	}
+	return "", NewInvalidInputError(fmt.Errorf("unknown resample mode %q, must be one of %s, %s, %s, %s", value, ResampleNearest, ResampleBox, ResampleBilinear, ResampleLanczos))
+}
+
+// resizeImage scales an image to exactly width x height pixels
+// uses the same Lanczos3 filter image2ascii scales with so all renderers look alike
+func resizeImage(m image.Image, width, height int) image.Image {
+	return resize.Resize(uint(width), uint(height), m, resize.Lanczos3)
+}
+
+// resample scales an image to exactly width x height pixels with the given filter
+// converters leave images that are already the size they need alone, so resampling up front replaces their own resampling
+// ResampleDefault returns m untouched
+func resample(ctx context.Context, m image.Image, width, height int, mode ResampleMode) (image.Image, error) {
+	bounds := m.Bounds()
+	if mode == ResampleDefault || (bounds.Dx() == width && bounds.Dy() == height) {
+		return m, nil
+	}
+	switch mode {
+	case ResampleBox:
+		src, err := toRGBA(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		err = forEachRow(ctx, height, func(y int) {
+			for x := 0; x < width; x++ {
+				dst.SetNRGBA(x, y, averageColor(src, cellBounds(src.Bounds(), x, y, width, height)))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dst, nil
+	case ResampleLanczos:
+		return resizeImage(m, width, height), nil
+	}
+	scaler := draw.Interpolator(draw.NearestNeighbor)
+	if mode == ResampleBilinear {
+		scaler = draw.BiLinear
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaler.Scale(dst, dst.Bounds(), m, bounds, draw.Src, nil)
+	return dst, ctx.Err()
+}
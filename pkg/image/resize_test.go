@@ -0,0 +1,115 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+var resampleModes = []ResampleMode{ResampleNearest, ResampleBox, ResampleBilinear, ResampleLanczos}
+
+func TestParseResampleMode(t *testing.T) {
+	for _, mode := range append(resampleModes, ResampleDefault) {
+		parsed, err := ParseResampleMode(string(mode))
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseResampleMode("bicubic")
+	assert.Error(t, err)
+}
+
+func TestResample(t *testing.T) {
+	// alternating black and white columns
+	m := image.NewNRGBA(image.Rect(0, 0, 8, 2))
+	for x := 0; x < 8; x += 2 {
+		for y := 0; y < 2; y++ {
+			m.Set(x, y, color.White)
+			m.Set(x+1, y, color.Black)
+		}
+	}
+
+	for _, mode := range resampleModes {
+		scaled, err := resample(context.Background(), m, 4, 1, mode)
+		assert.NoError(t, err, mode)
+		assert.Equal(t, image.Rect(0, 0, 4, 1), scaled.Bounds(), mode)
+	}
+
+	// nearest neighbour keeps one of the columns, box averages them out to gray
+	scaled, err := resample(context.Background(), m, 4, 1, ResampleNearest)
+	assert.NoError(t, err)
+	r, _, _, _ := scaled.At(0, 0).RGBA()
+	assert.True(t, r == 0 || r == 0xffff, "%d", r)
+	scaled, err = resample(context.Background(), m, 4, 1, ResampleBox)
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 127, G: 127, B: 127, A: 255}, scaled.At(0, 0))
+
+	// the converter's own resampling is left alone, as are images that are already the right size
+	same, err := resample(context.Background(), m, 4, 1, ResampleDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Image(m), same)
+	same, err = resample(context.Background(), m, 8, 2, ResampleBox)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Image(m), same)
+}
+
+func TestResample_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := resample(ctx, image.NewNRGBA(image.Rect(0, 0, 8, 8)), 4, 4, ResampleBox)
+
+	assert.Error(t, err)
+}
+
+func TestResample_Golden(t *testing.T) {
+	m := loadTestImage(t, "worldview_3.png")
+	for _, mode := range []RenderMode{ModeNative, ModeBraille} {
+		converter, _ := LookupConverter(mode)
+		for _, resampleMode := range resampleModes {
+			opts := ConversionOptions{Mode: mode, Width: 80, Resample: resampleMode}.withDefaults()
+			cellWidth, cellHeight := converter.CellSize()
+			width, height := opts.targetSize(m.Bounds(), cellWidth)
+			scaled, err := resample(context.Background(), m, width*cellWidth, height*cellHeight, resampleMode)
+			assert.NoError(t, err)
+
+			grid, err := converter.Convert(context.Background(), scaled, width, height, opts)
+
+			assert.NoError(t, err)
+			assertGolden(t, fmt.Sprintf("worldview_3_%s_resample_%s.txt", mode, resampleMode), grid)
+		}
+	}
+}
+
+func TestService_NewASCIIImageSyncE2E_Resample(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+
+	id, _, err := service.NewASCIIImageSync(context.Background(), getGoodImageRCloser(), ConversionOptions{Width: 8, Resample: ResampleBox})
+	assert.NoError(t, err)
+
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, ResampleBox, asciiImage.Options.Resample)
+	assert.Equal(t, 8, asciiImage.Options.Width)
+	assert.Equal(t, 4, asciiImage.Options.Height)
+}
+
+func benchmarkResample(b *testing.B, mode ResampleMode, width int) {
+	m := loadBenchmarkImage(b)
+	opts := ConversionOptions{Width: width}.withDefaults()
+	width, height := opts.targetSize(m.Bounds(), 1)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := resample(context.Background(), m, width, height, mode); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResample_Nearest_200(b *testing.B)  { benchmarkResample(b, ResampleNearest, 200) }
+func BenchmarkResample_Box_200(b *testing.B)      { benchmarkResample(b, ResampleBox, 200) }
+func BenchmarkResample_Bilinear_200(b *testing.B) { benchmarkResample(b, ResampleBilinear, 200) }
+func BenchmarkResample_Lanczos_200(b *testing.B)  { benchmarkResample(b, ResampleLanczos, 200) }
@@ -0,0 +1,247 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Crop is the rectangle of the image that's converted
+// coordinates are pixels, or percentages of the image's width and height if Percent is set
+// the zero Crop converts the whole image
+type Crop struct {
+	X, Y, Width, Height float64
+	Percent             bool
+}
+
+// ParseCrop parses a user supplied crop rectangle formatted as x,y,width,height
+// either all values are pixels (i.e 10,20,300,200) or all are percentages (i.e 10%,10%,80%,80%)
+func ParseCrop(value string) (Crop, error) {
+	var crop Crop
+	if value == "" {
+		return crop, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return crop, NewInvalidInputError(fmt.Errorf("crop must be formatted as x,y,width,height, got %q", value))
+	}
+	percentages := 0
+	values := make([]float64, 4)
+	for n, part := range parts {
+		part = strings.TrimSpace(part)
+		if strings.HasSuffix(part, "%") {
+			percentages++
+			part = strings.TrimSuffix(part, "%")
+		}
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return crop, NewInvalidInputError(fmt.Errorf("crop values must be numbers, got %q", parts[n]))
+		}
+		values[n] = f
+	}
+	if percentages != 0 && percentages != 4 {
+		return crop, NewInvalidInputError(fmt.Errorf("crop values must either all be pixels or all be percentages"))
+	}
+	crop = Crop{X: values[0], Y: values[1], Width: values[2], Height: values[3], Percent: percentages == 4}
+	return crop, crop.validate()
+}
+
+func (c Crop) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	format := func(f float64) string {
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if c.Percent {
+			s += "%"
+		}
+		return s
+	}
+	return strings.Join([]string{format(c.X), format(c.Y), format(c.Width), format(c.Height)}, ",")
+}
+
+func (c Crop) IsZero() bool {
+	return c == Crop{}
+}
+
+func (c Crop) validate() error {
+	if c.IsZero() {
+		return nil
+	}
+	for _, f := range []float64{c.X, c.Y, c.Width, c.Height} {
+		if f < 0 || math.IsNaN(f) || math.IsInf(f, 0) || (c.Percent && f > 100) {
+			return NewInvalidInputError(fmt.Errorf("crop values must be positive, and at most 100 for percentages"))
+		}
+	}
+	if c.Width <= 0 || c.Height <= 0 {
+		return NewInvalidInputError(fmt.Errorf("crop width and height must be greater than 0"))
+	}
+	return nil
+}
+
+// rect resolves the crop against the bounds of the image it's applied to
+// returns an InvalidInputError if the crop doesn't overlap the image at all
+func (c Crop) rect(bounds image.Rectangle) (image.Rectangle, error) {
+	if c.IsZero() {
+		return bounds, nil
+	}
+	x, y, width, height := c.X, c.Y, c.Width, c.Height
+	if c.Percent {
+		dx, dy := float64(bounds.Dx())/100, float64(bounds.Dy())/100
+		x, y, width, height = x*dx, y*dy, width*dx, height*dy
+	}
+	rect := image.Rect(int(x), int(y), int(math.Ceil(x+width)), int(math.Ceil(y+height))).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return rect, NewInvalidInputError(fmt.Errorf("crop %s is outside of the %dx%d image", c, bounds.Dx(), bounds.Dy()))
+	}
+	return rect, nil
+}
+
+// FlipMode mirrors the image
+type FlipMode string
+
+const (
+	FlipNone       FlipMode = ""
+	FlipHorizontal FlipMode = "horizontal"
+	FlipVertical   FlipMode = "vertical"
+	FlipBoth       FlipMode = "both"
+)
+
+// ParseFlipMode parses a user supplied flip mode, "none" and "" both mean no flip
+func ParseFlipMode(value string) (FlipMode, error) {
+	switch mode := FlipMode(value); mode {
+	case FlipNone, FlipHorizontal, FlipVertical, FlipBoth:
+		return mode, nil
+	case "none":
+		return FlipNone, nil
+	}
+	return FlipNone, NewInvalidInputError(fmt.Errorf("unknown flip mode %q, must be one of none, %s, %s, %s", value, FlipHorizontal, FlipVertical, FlipBoth))
+}
+
+// ParseRotation parses a user supplied clockwise rotation in degrees, which must be 0, 90, 180 or 270
+func ParseRotation(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	degrees, err := strconv.Atoi(value)
+	if err != nil || validateRotation(degrees) != nil {
+		return 0, NewInvalidInputError(fmt.Errorf("rotate must be one of 0, 90, 180 or 270, got %q", value))
+	}
+	return degrees, nil
+}
+
+func validateRotation(degrees int) error {
+	switch degrees {
+	case 0, 90, 180, 270:
+		return nil
+	}
+	return NewInvalidInputError(fmt.Errorf("rotate must be one of 0, 90, 180 or 270"))
+}
+
+// orientation is a clockwise rotation followed by an optional horizontal mirror
+// any combination of 90 degree rotations and flips (including all 8 exif orientations) can be expressed as one
+type orientation struct {
+	rotate int
+	mirror bool
+}
+
+// exifOrientations maps exif orientation values onto the transform that shows the image upright
+var exifOrientations = map[int]orientation{
+	1: {0, false},
+	2: {0, true},
+	3: {180, false},
+	4: {180, true},
+	5: {90, true},
+	6: {90, false},
+	7: {270, true},
+	8: {270, false},
+}
+
+// userOrientation is the transform for the user supplied rotation and flip
+// a vertical flip is a horizontal flip rotated by 180 degrees
+func userOrientation(rotate int, flip FlipMode) orientation {
+	o := orientation{rotate: rotate}
+	switch flip {
+	case FlipHorizontal:
+		o.mirror = true
+	case FlipVertical:
+		o.rotate, o.mirror = (rotate+180)%360, true
+	case FlipBoth:
+		o.rotate = (rotate + 180) % 360
+	}
+	return o
+}
+
+func (o orientation) isIdentity() bool {
+	return o.rotate == 0 && !o.mirror
+}
+
+// transform copies the src rectangle of m into a new image with its origin at 0,0, rotated and mirrored according to o
+func transform(ctx context.Context, m image.Image, src image.Rectangle, o orientation) (*image.NRGBA, error) {
+	in := image.NewNRGBA(image.Rect(0, 0, src.Dx(), src.Dy()))
+	draw.Draw(in, in.Bounds(), m, src.Min, draw.Src)
+	if o.isIdentity() {
+		return in, nil
+	}
+
+	width, height := src.Dx(), src.Dy()
+	if o.rotate == 90 || o.rotate == 270 {
+		width, height = height, width
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	err := forEachRow(ctx, height, func(y int) {
+		for x := 0; x < width; x++ {
+			dx := x
+			if o.mirror {
+				dx = width - 1 - x
+			}
+			// walk back from the destination pixel to the source pixel it was rotated from
+			var sx, sy int
+			switch o.rotate {
+			case 0:
+				sx, sy = dx, y
+			case 90:
+				sx, sy = y, src.Dy()-1-dx
+			case 180:
+				sx, sy = src.Dx()-1-dx, src.Dy()-1-y
+			case 270:
+				sx, sy = src.Dx()-1-y, dx
+			}
+			copy(out.Pix[out.PixOffset(x, y):out.PixOffset(x, y)+4], in.Pix[in.PixOffset(sx, sy):in.PixOffset(sx, sy)+4])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// applyGeometry turns the image upright according to its exif orientation, then crops, rotates and flips it as requested
+// returns m untouched if there's nothing to do
+func applyGeometry(ctx context.Context, m image.Image, exifOrientation int, opts ConversionOptions) (image.Image, error) {
+	upright, k := exifOrientations[exifOrientation]
+	if opts.IgnoreOrientation || !k {
+		upright = orientation{}
+	}
+	requested := userOrientation(opts.Rotate, opts.Flip)
+	if upright.isIdentity() && requested.isIdentity() && opts.Crop.IsZero() {
+		return m, nil
+	}
+
+	var err error
+	if !upright.isIdentity() {
+		if m, err = transform(ctx, m, m.Bounds(), upright); err != nil {
+			return nil, err
+		}
+	}
+	// crop coordinates are relative to the upright image
+	rect, err := opts.Crop.rect(m.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	return transform(ctx, m, rect, requested)
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// letterImage builds an image out of rows of letters, each letter is a pixel with a distinct gray value
+func letterImage(rows ...string) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, letter := range row {
+			m.Set(x, y, color.Gray{Y: uint8(letter)})
+		}
+	}
+	return m
+}
+
+func imageLetters(m image.Image) []string {
+	var rows []string
+	for y := m.Bounds().Min.Y; y < m.Bounds().Max.Y; y++ {
+		var sb strings.Builder
+		for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
+			sb.WriteByte(color.GrayModel.Convert(m.At(x, y)).(color.Gray).Y)
+		}
+		rows = append(rows, sb.String())
+	}
+	return rows
+}
+
+func TestTransform(t *testing.T) {
+	cases := []struct {
+		name     string
+		o        orientation
+		expected []string
+	}{
+		{"rotate 90", userOrientation(90, FlipNone), []string{"da", "eb", "fc"}},
+		{"rotate 180", userOrientation(180, FlipNone), []string{"fed", "cba"}},
+		{"rotate 270", userOrientation(270, FlipNone), []string{"cf", "be", "ad"}},
+		{"flip horizontal", userOrientation(0, FlipHorizontal), []string{"cba", "fed"}},
+		{"flip vertical", userOrientation(0, FlipVertical), []string{"def", "abc"}},
+		{"flip both", userOrientation(0, FlipBoth), []string{"fed", "cba"}},
+		{"rotate 90 flip vertical", userOrientation(90, FlipVertical), []string{"fc", "eb", "da"}},
+		{"exif transpose", exifOrientations[5], []string{"ad", "be", "cf"}},
+		{"exif transverse", exifOrientations[7], []string{"fc", "eb", "da"}},
+	}
+	m := letterImage("abc", "def")
+	for _, c := range cases {
+		out, err := transform(context.Background(), m, m.Bounds(), c.o)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.expected, imageLetters(out), c.name)
+	}
+}
+
+func TestApplyGeometry_Crop(t *testing.T) {
+	m := letterImage("abcd", "efgh", "ijkl", "mnop")
+
+	out, err := applyGeometry(context.Background(), m, orientationNormal, ConversionOptions{Crop: Crop{X: 1, Y: 1, Width: 2, Height: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), out.Bounds())
+	assert.Equal(t, []string{"fg", "jk"}, imageLetters(out))
+
+	out, err = applyGeometry(context.Background(), m, orientationNormal, ConversionOptions{Crop: Crop{X: 50, Y: 0, Width: 50, Height: 25, Percent: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cd"}, imageLetters(out))
+
+	// crops are clamped to the image
+	out, err = applyGeometry(context.Background(), m, orientationNormal, ConversionOptions{Crop: Crop{X: 3, Y: 3, Width: 10, Height: 10}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p"}, imageLetters(out))
+
+	_, err = applyGeometry(context.Background(), m, orientationNormal, ConversionOptions{Crop: Crop{X: 10, Y: 10, Width: 1, Height: 1}})
+	_, isInputError := err.(InvalidInputError)
+	assert.True(t, isInputError)
+}
+
+func TestApplyGeometry_Orientation(t *testing.T) {
+	// stored sideways, orientation 6 means it needs rotating 90 degrees clockwise
+	m := letterImage("cf", "be", "ad")
+
+	out, err := applyGeometry(context.Background(), m, 6, ConversionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc", "def"}, imageLetters(out))
+
+	// cropping happens on the upright image, before the requested rotation
+	out, err = applyGeometry(context.Background(), m, 6, ConversionOptions{Crop: Crop{X: 1, Y: 0, Width: 2, Height: 2}, Rotate: 180})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fe", "cb"}, imageLetters(out))
+
+	out, err = applyGeometry(context.Background(), m, 6, ConversionOptions{IgnoreOrientation: true})
+	assert.NoError(t, err)
+	assert.Equal(t, m, out)
+}
+
+func TestParseCrop(t *testing.T) {
+	crop, err := ParseCrop("10, 20,300,200")
+	assert.NoError(t, err)
+	assert.Equal(t, Crop{X: 10, Y: 20, Width: 300, Height: 200}, crop)
+	assert.Equal(t, "10,20,300,200", crop.String())
+
+	crop, err = ParseCrop("10%,10%,80%,80.5%")
+	assert.NoError(t, err)
+	assert.Equal(t, Crop{X: 10, Y: 10, Width: 80, Height: 80.5, Percent: true}, crop)
+	assert.Equal(t, "10%,10%,80%,80.5%", crop.String())
+
+	for _, value := range []string{"1,2,3", "a,b,c,d", "10%,10,80%,80%", "0,0,0,10", "-1,0,10,10", "0%,0%,150%,10%"} {
+		_, err := ParseCrop(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// jpegWithOrientation encodes m as a jpeg with an exif segment holding the given orientation
+func jpegWithOrientation(t *testing.T, m image.Image, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	entry := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(entry[0:], 1)
+	binary.BigEndian.PutUint16(entry[2:], exifOrientationTag)
+	binary.BigEndian.PutUint16(entry[4:], 3)
+	binary.BigEndian.PutUint32(entry[6:], 1)
+	binary.BigEndian.PutUint16(entry[10:], orientation)
+	segment := append([]byte("Exif\x00\x00"), append(tiff, entry...)...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, segment...)...), data[2:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 4, 2))
+	assert.Equal(t, 6, exifOrientation(jpegWithOrientation(t, m, 6), "jpeg"))
+	assert.Equal(t, orientationNormal, exifOrientation(jpegWithOrientation(t, m, 42), "jpeg"))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, jpeg.Encode(buf, m, nil))
+	assert.Equal(t, orientationNormal, exifOrientation(buf.Bytes(), "jpeg"))
+	assert.Equal(t, orientationNormal, exifOrientation([]byte("garbage"), "png"))
+}
+
+func TestService_NewASCIIImageSyncE2E_Orientation(t *testing.T) {
+	service := NewService(&MockImageStore{data: make(map[uuid.UUID]ASCIIImage)})
+	// a wide image stored sideways
+	data := jpegWithOrientation(t, image.NewGray(image.Rect(0, 0, 16, 8)), 6)
+
+	id, format, err := service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(data)), ConversionOptions{Mode: ModeNative})
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	_, asciiImage, err := service.GetASCIIImage(context.Background(), *id)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, asciiImage.Options.Width)
+	// halved for the default cell aspect
+	assert.Equal(t, 8, asciiImage.Options.Height)
+
+	_, _, err = service.NewASCIIImageSync(context.Background(), ioutil.NopCloser(bytes.NewReader(data)), ConversionOptions{Crop: Crop{X: 100, Y: 100, Width: 1, Height: 1}})
+	_, isInputError := err.(InvalidInputError)
+	assert.True(t, isInputError)
+}
@@ -1,9 +1,40 @@
 package image
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type ImageStore interface {
-	PushASCIIImage(asciiImage string, id uuid.UUID) error
-	GetASCIIImage(id uuid.UUID) (bool, string, error)
+	PushASCIIImage(asciiImage ASCIIImage, id uuid.UUID) error
+	GetASCIIImage(id uuid.UUID) (bool, *ASCIIImage, error)
+	// GetASCIIImageCells is GetASCIIImage with the Cells of the image and its frames loaded, stores may leave them out of GetASCIIImage
+	GetASCIIImageCells(id uuid.UUID) (bool, *ASCIIImage, error)
 	ListASCIIImages() ([]uuid.UUID, error)
 }
+
+// ASCIIImage is a rendered ascii image along with the options it was rendered with
+type ASCIIImage struct {
+	Value string
+	// ANSIValue is the ANSI colored rendition of the image, only set if Options.Color is set
+	ANSIValue string
+	// SourceFormat is the format of the uploaded image, i.e png or jpeg
+	SourceFormat string
+	Options      ConversionOptions
+	// Frames are the rendered frames of an animated image, only set if Options.Animate is set and the image has more than one frame
+	// Value/ANSIValue hold the first frame
+	Frames []Frame
+	// LoopCount is the number of times an animated image plays, 0 means forever
+	LoopCount int
+	// Cells are the rendered cells of the image (the first frame of animated images), nil for imported images and images stored before cells were kept
+	Cells Grid
+}
+
+// Frame is a single rendered frame of an animated image
+type Frame struct {
+	Value     string
+	ANSIValue string
+	// Delay is how long the frame is shown for before moving on to the next frame
+	Delay time.Duration
+	Cells Grid
+}
@@ -7,14 +7,107 @@ type ErrorResponse struct {
 
 type NewImageResponse struct {
 	ImageID string
+	// Format is the detected format of the uploaded image
+	Format string
 }
 
 type GetImageResponse struct {
-	ASCIIValue   string
-	Finished     bool
-	ErrorMessage string
+	ASCIIValue    string
+	ANSIValue     string
+	SourceFormat  string
+	Finished      bool
+	ErrorMessage  string
+	RenderOptions RenderOptions
+}
+
+// RenderOptions are the options an image was rendered with
+type RenderOptions struct {
+	Mode      string
+	Width     int
+	Height    int
+	Scale     float64
+	Color     string
+	Ramp      string
+	Invert    bool
+	Animate   bool
+	Threshold *float64
+	Dither    string
+	// Adjustments are the preprocessing steps applied before conversion, in order, formatted like the adjust param (i.e gamma:1.4)
+	Adjustments []string
+	// Crop is formatted like the crop param (i.e 10%,10%,80%,80%), empty if the whole image was converted
+	Crop   string
+	Rotate int
+	Flip   string
+	// IgnoreOrientation is set if the image wasn't turned upright according to its exif orientation
+	IgnoreOrientation bool
+	// CellAspect is the width/height ratio of the character cells the image was sized for
+	CellAspect float64
+	Alpha      string
+	// Background is the #rrggbb color transparent pixels were drawn over with the composite alpha mode
+	Background     string
+	AlphaThreshold *float64
+	EdgeDetector   string
+	EdgeOverlay    bool
+	EmojiPalette   string
+	ColorDither    string
+	Resample       string
+	Title          string
+	Author         string
+}
+
+type GetImageFramesResponse struct {
+	Frames []Frame
+	// LoopCount is the number of times the animation plays, 0 means forever
+	LoopCount int
+}
+
+type Frame struct {
+	ASCIIValue string
+	ANSIValue  string
+	DelayMs    int64
 }
 
 type GetImageListResponse struct {
 	ImageIDList []string
 }
+
+// GetImageCellsResponse is every character of an image along with its colors, for clients that render images themselves
+type GetImageCellsResponse struct {
+	Width  int
+	Height int
+	Rows   [][]Cell
+	// Frames are the cells of every frame of an animated image, Rows holds the first frame
+	Frames    []CellsFrame
+	LoopCount int
+}
+
+type CellsFrame struct {
+	Rows    [][]Cell
+	DelayMs int64
+}
+
+// CellsRow is a single row of cells, the ndjson format has one per line
+type CellsRow struct {
+	// Frame is the index of the frame the row belongs to, always 0 for still images
+	Frame int
+	Row   int
+	Cells []Cell
+}
+
+type Cell struct {
+	// Glyph is the character drawn in the cell, with Foreground as its color over Background
+	Glyph string
+	// Foreground is unset for transparent cells
+	Foreground *RGB
+	// Background is unset where the cell has no background of its own
+	Background *RGB
+	// Luminance is the brightness (0-1) of the source pixels the cell was rendered from, unset for imported images
+	Luminance   *float64
+	Transparent bool
+}
+
+type RGB struct {
+	R uint8
+	G uint8
+	B uint8
+}
@@ -6,7 +6,7 @@ echo "1. check if service is running"
 curl localhost:8000/health
 
 echo "2. list all currently saved images"
-curl localhost:8000/images | jq '.ImageIDList[]'
+curl localhost:8000/images
 
 echo "3. add new images and grab them"
 for png in test/data/*.png; do
@@ -14,9 +14,8 @@ for png in test/data/*.png; do
   response=$(curl -XPOST --data-binary @${png} localhost:8000/images)
   id=$(echo $response | jq -r '.ImageID')
   echo "grabbing ascii image for $id"
-  imageresponse=$(curl localhost:8000/images/"${id}")
-  image=$(echo $imageresponse | jq -r ".ASCIIValue")
-  echo $image
+  # curl gets the raw ascii image back
+  curl localhost:8000/images/"${id}"
 done
 
 echo "4. add new images using async and grab them"
@@ -26,11 +25,10 @@ for png in test/data/*.png; do
   id=$(echo $response | jq -r '.ImageID')
   echo "grabbing ascii image for $id"
   while true; do
-      imageresponse=$(curl localhost:8000/images/"${id}")
+      imageresponse=$(curl -H "Accept: application/json" localhost:8000/images/"${id}")
       finished=$(echo $imageresponse | jq ".Finished")
       if [[ "${finished}" == "true" ]]; then
-        image=$(echo $imageresponse | jq -r ".ASCIIValue")
-        echo $image
+        curl localhost:8000/images/"${id}"
         break
       fi
       echo "image not yet generated...wait for a bit"
@@ -45,6 +43,6 @@ for png in test/baddata/*; do
 done
 
 echo "6. list all currently saved images again"
-curl localhost:8000/images | jq '.ImageIDList[]'
+curl localhost:8000/images
 
 echo "done"
\ No newline at end of file